@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rrgmc/helm-render-ui/helm"
+	"github.com/urfave/cli/v3"
+)
+
+// defaultIndexTTL is how long the cached repository index files are used by default.
+const defaultIndexTTL = time.Hour
+
+func cacheCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "cache",
+		Usage: "manage the cache of repository index files and charts",
+		Commands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "list the cached repository index files and charts",
+				Flags: []cli.Flag{
+					cacheDirFlag(),
+				},
+				Action: func(ctx context.Context, command *cli.Command) error {
+					cache, err := cacheFromFlags(command)
+					if err != nil {
+						return err
+					}
+					entries, err := cache.Entries()
+					if err != nil {
+						return err
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					_, _ = fmt.Fprintln(w, "KIND\tREPOSITORY\tCHART\tVERSION\tSIZE\tMODIFIED")
+					for _, entry := range entries {
+						_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", entry.Kind, entry.Repository, entry.Chart,
+							entry.Version, entry.Size, entry.Modified.Format(time.DateTime))
+					}
+					return w.Flush()
+				},
+			},
+			{
+				Name:  "prune",
+				Usage: "remove the cached repository index files and charts",
+				Flags: []cli.Flag{
+					cacheDirFlag(),
+					&cli.DurationFlag{
+						Name:  "older-than",
+						Usage: "only remove the entries not used in this duration",
+					},
+				},
+				Action: func(ctx context.Context, command *cli.Command) error {
+					cache, err := cacheFromFlags(command)
+					if err != nil {
+						return err
+					}
+					removed, err := cache.Prune(command.Duration("older-than"))
+					if err != nil {
+						return fmt.Errorf("error pruning cache: %w", err)
+					}
+					slog.InfoContext(ctx, "cache pruned", "dir", cache.Dir(), "removed", len(removed))
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func cacheDirFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "cache-dir",
+		Usage:   "folder of the repository index files and charts cache (default: helm-render-ui in the user cache folder)",
+		Sources: cli.EnvVars("HELM_RENDER_UI_CACHE_DIR"),
+	}
+}
+
+func cacheDirFromFlags(command *cli.Command) (string, error) {
+	if cacheDir := command.String("cache-dir"); cacheDir != "" {
+		return cacheDir, nil
+	}
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting the cache folder, use --cache-dir: %w", err)
+	}
+	return filepath.Join(userCacheDir, "helm-render-ui"), nil
+}
+
+func cacheFromFlags(command *cli.Command) (*helm.Cache, error) {
+	cacheDir, err := cacheDirFromFlags(command)
+	if err != nil {
+		return nil, err
+	}
+	return helm.NewCache(cacheDir), nil
+}
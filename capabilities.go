@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"sigs.k8s.io/yaml"
+)
+
+// capabilitiesFile is the format of the file passed to "--capabilities-file".
+//
+//	kubeVersion: v1.29.3
+//	apiVersions:
+//	  - monitoring.coreos.com/v1
+//	  - monitoring.coreos.com/v1/ServiceMonitor
+type capabilitiesFile struct {
+	// KubeVersion is the Kubernetes version.
+	KubeVersion string `json:"kubeVersion"`
+	// APIVersions are added to the API versions that Helm knows by default.
+	APIVersions []string `json:"apiVersions"`
+}
+
+// loadCapabilities builds the capabilities from the Helm defaults, the capabilities file and the command line
+// flags, in this order.
+func loadCapabilities(filename string, kubeVersion string, apiVersions []string) (*chartutil.Capabilities, error) {
+	caps := chartutil.DefaultCapabilities.Copy()
+	caps.APIVersions = slices.Clone(caps.APIVersions)
+
+	if filename != "" {
+		bytes, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		var file capabilitiesFile
+		if err := yaml.Unmarshal(bytes, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse capabilities file %s: %w", filename, err)
+		}
+		if err := setCapabilities(caps, file.KubeVersion, file.APIVersions); err != nil {
+			return nil, fmt.Errorf("invalid capabilities file %s: %w", filename, err)
+		}
+	}
+
+	if err := setCapabilities(caps, kubeVersion, apiVersions); err != nil {
+		return nil, err
+	}
+
+	return caps, nil
+}
+
+func setCapabilities(caps *chartutil.Capabilities, kubeVersion string, apiVersions []string) error {
+	if kubeVersion != "" {
+		kv, err := chartutil.ParseKubeVersion(kubeVersion)
+		if err != nil {
+			return fmt.Errorf("invalid kube version '%s': %w", kubeVersion, err)
+		}
+		caps.KubeVersion = *kv
+	}
+	for _, apiVersion := range apiVersions {
+		if !caps.APIVersions.Has(apiVersion) {
+			caps.APIVersions = append(caps.APIVersions, apiVersion)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rrgmc/helm-render-ui/helm"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"sigs.k8s.io/yaml"
+)
+
+// projectConfigFilename is the name of the project config file, searched in the chart folder and its parents up to
+// the repository root.
+const projectConfigFilename = ".helm-render-ui.yaml"
+
+// projectConfig is the project config file, with named environments.
+type projectConfig struct {
+	Environments map[string]projectEnvironment `json:"environments"`
+
+	filename string
+}
+
+// projectEnvironment are the render settings of an environment. File paths are relative to the config file.
+type projectEnvironment struct {
+	// Chart is the chart folder, or the chart name when using a repository. The chart argument takes precedence.
+	Chart          string   `json:"chart"`
+	Namespace      string   `json:"namespace"`
+	Release        string   `json:"release"`
+	Values         []string `json:"values"`
+	Set            []string `json:"set"`
+	SetString      []string `json:"setString"`
+	SetFile        []string `json:"setFile"`
+	SetJSON        []string `json:"setJSON"`
+	SetLiteral     []string `json:"setLiteral"`
+	KubeVersion    string   `json:"kubeVersion"`
+	APIVersions    []string `json:"apiVersions"`
+	Repo           string   `json:"repo"`
+	ChartVersion   string   `json:"chartVersion"`
+	LookupFixtures []string `json:"lookupFixtures"`
+}
+
+// findProjectConfig searches the project config file in the folder and its parents, stopping at the repository
+// root (the folder containing ".git"). It returns an empty string if not found.
+func findProjectConfig(folder string) (string, error) {
+	absFolder, err := filepath.Abs(folder)
+	if err != nil {
+		return "", err
+	}
+	for {
+		filename := filepath.Join(absFolder, projectConfigFilename)
+		if _, err := os.Stat(filename); err == nil {
+			return filename, nil
+		}
+		if _, err := os.Stat(filepath.Join(absFolder, ".git")); err == nil {
+			return "", nil
+		}
+		parent := filepath.Dir(absFolder)
+		if parent == absFolder {
+			return "", nil
+		}
+		absFolder = parent
+	}
+}
+
+// loadProjectConfig loads the project config file, making the file paths absolute.
+func loadProjectConfig(filename string) (*projectConfig, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var ret projectConfig
+	if err := yaml.UnmarshalStrict(content, &ret); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	ret.filename = filename
+
+	folder := filepath.Dir(filename)
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(folder, path)
+	}
+	// chart archive URLs and OCI references are not paths, and neither are the "repo/chart" references to
+	// repositories added with "helm repo add", if a folder with the path doesn't exist.
+	resolveChart := func(chart string) string {
+		if chart == "" || isRepositoryURL(chart) {
+			return chart
+		}
+		resolved := resolve(chart)
+		if _, err := os.Stat(resolved); err != nil {
+			if _, _, ok := splitRepositoryChartName(chart, helm.HasRepositoryEntry); ok {
+				return chart
+			}
+		}
+		return resolved
+	}
+	for name, env := range ret.Environments {
+		if env.Repo == "" {
+			env.Chart = resolveChart(env.Chart)
+		}
+		for idx, valueFile := range env.Values {
+			env.Values[idx] = resolve(valueFile)
+		}
+		for idx, setFile := range env.SetFile {
+			if key, path, ok := strings.Cut(setFile, "="); ok {
+				env.SetFile[idx] = key + "=" + resolve(path)
+			}
+		}
+		for idx, fixture := range env.LookupFixtures {
+			env.LookupFixtures[idx] = resolve(fixture)
+		}
+		ret.Environments[name] = env
+	}
+	return &ret, nil
+}
+
+// Environment returns the named environment.
+func (c *projectConfig) Environment(name string) (projectEnvironment, error) {
+	env, ok := c.Environments[name]
+	if !ok {
+		return projectEnvironment{}, fmt.Errorf("environment '%s' not found in %s", name, c.filename)
+	}
+	return env, nil
+}
+
+// EnvironmentNames returns the environment names, sorted.
+func (c *projectConfig) EnvironmentNames() []string {
+	if c == nil {
+		return nil
+	}
+	var ret []string
+	for name := range mapSortedByKey(c.Environments) {
+		ret = append(ret, name)
+	}
+	return ret
+}
+
+// mergeValuesOptions returns the environment values followed by the flag values, which take precedence.
+func (e projectEnvironment) mergeValuesOptions(options values.Options) values.Options {
+	return values.Options{
+		ValueFiles:    slices.Concat(e.Values, options.ValueFiles),
+		Values:        slices.Concat(e.Set, options.Values),
+		StringValues:  slices.Concat(e.SetString, options.StringValues),
+		FileValues:    slices.Concat(e.SetFile, options.FileValues),
+		JSONValues:    slices.Concat(e.SetJSON, options.JSONValues),
+		LiteralValues: slices.Concat(e.SetLiteral, options.LiteralValues),
+	}
+}
+
+// loadProjectConfigForChart loads the config file, or searches for it starting from the chart folder or the
+// current folder if the chart is not a local folder. It returns nil if no config file was found.
+func loadProjectConfigForChart(configFile string, chartFolder string) (*projectConfig, error) {
+	if configFile == "" {
+		searchFolder := "."
+		if st, err := os.Stat(chartFolder); err == nil && st.IsDir() {
+			searchFolder = chartFolder
+		}
+		var err error
+		configFile, err = findProjectConfig(searchFolder)
+		if err != nil {
+			return nil, err
+		}
+		if configFile == "" {
+			return nil, nil
+		}
+	}
+
+	config, err := loadProjectConfig(configFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("config file %s not found", configFile)
+		}
+		return nil, err
+	}
+	return config, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadProjectConfigChart(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "myrepo", "local"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	repositoryConfig := filepath.Join(t.TempDir(), "repositories.yaml")
+	err := os.WriteFile(repositoryConfig, []byte("repositories:\n- name: myrepo\n  url: https://charts.example.com\n"),
+		0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HELM_REPOSITORY_CONFIG", repositoryConfig)
+
+	tests := []struct {
+		name  string
+		chart string
+		want  string
+	}{
+		{name: "folder", chart: "./mychart", want: filepath.Join(dir, "mychart")},
+		{name: "archive", chart: "mychart-1.0.0.tgz", want: filepath.Join(dir, "mychart-1.0.0.tgz")},
+		{name: "absolute", chart: "/charts/mychart", want: "/charts/mychart"},
+		{name: "archive URL", chart: "https://charts.example.com/mychart-1.0.0.tgz",
+			want: "https://charts.example.com/mychart-1.0.0.tgz"},
+		{name: "repository chart", chart: "myrepo/mychart", want: "myrepo/mychart"},
+		{name: "unknown repository", chart: "otherrepo/mychart", want: filepath.Join(dir, "otherrepo", "mychart")},
+		// an existing folder takes precedence.
+		{name: "repository named folder", chart: "myrepo/local", want: filepath.Join(dir, "myrepo", "local")},
+		{name: "OCI reference", chart: "oci://localhost:1/charts/mychart:1.0.0",
+			want: "oci://localhost:1/charts/mychart:1.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, projectConfigFilename)
+			content := "environments:\n  dev:\n    chart: " + tt.chart + "\n"
+			if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := loadProjectConfig(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			env, err := config.Environment("dev")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if env.Chart != tt.want {
+				t.Fatalf("expected chart %q, got %q", tt.want, env.Chart)
+			}
+		})
+	}
+}
@@ -1,15 +1,217 @@
 package main
 
 type apiData struct {
-	Chart        string        `json:"chart"`
-	Release      string        `json:"release"`
-	Values       string        `json:"values"`
-	FullValues   string        `json:"fullValues"`
-	RenderValues string        `json:"renderValues"`
-	PreviewFiles []apiDataFile `json:"previewFiles"`
+	Chart         string                `json:"chart"`
+	Release       string                `json:"release"`
+	Values        string                `json:"values"`
+	FullValues    string                `json:"fullValues"`
+	RenderValues  string                `json:"renderValues"`
+	Capabilities  string                `json:"capabilities"`
+	PreviewFiles  []apiDataFile         `json:"previewFiles"`
+	Objects       []apiDataObject       `json:"objects"`
+	InstallOrder  []apiDataObject       `json:"installOrder"`
+	Hooks         []apiDataHookEvent    `json:"hooks"`
+	ValuesErrors  []apiDataValuesError  `json:"valuesErrors,omitempty"`
+	PostRender    *apiDataPostRender    `json:"postRender,omitempty"`
+	Lookups       []apiDataLookup       `json:"lookups,omitempty"`
+	ChartVersion  string                `json:"chartVersion,omitempty"`
+	ChartVersions []apiDataChartVersion `json:"chartVersions,omitempty"`
+	Environment   string                `json:"environment,omitempty"`
+	Environments  []string              `json:"environments,omitempty"`
+	Dependencies  []apiDataDependency   `json:"dependencies,omitempty"`
+	ValueSources  []apiDataValueSource  `json:"valueSources,omitempty"`
+	Compare       bool                  `json:"compare,omitempty"`
+	Error         *apiDataError         `json:"error,omitempty"`
+}
+
+// apiExport is the data saved in an exported page, in place of the server API.
+type apiExport struct {
+	Data apiData      `json:"data"`
+	Diff *apiDataDiff `json:"diff,omitempty"`
+}
+
+// apiDataError describes an error loading or rendering the chart. The template location fields are only set when
+// the error comes from a template.
+type apiDataError struct {
+	Message  string `json:"message"`
+	Template string `json:"template,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Snippet  string `json:"snippet,omitempty"`
+}
+
+// apiRenderRequest is the body of the render request. Values and Release are YAML documents in the same format as
+// the "values" and "release" fields of apiData. An empty Release uses the release options from the command line.
+type apiRenderRequest struct {
+	Values  string `json:"values"`
+	Release string `json:"release"`
+}
+
+// apiDataValuesError is a violation of a values.schema.json. Source and Line are where the value was set, when
+// it can be found.
+type apiDataValuesError struct {
+	Chart   string `json:"chart"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	Source  string `json:"source,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// apiDataDependency is a dependency of the chart. Version is the version range of "Chart.yaml" and ChartVersion the
+// version of the subchart that was loaded, Missing is set when no subchart matches the dependency. Reason is the tag
+// or condition that decided the enabled state, and is empty when it is enabled by default.
+type apiDataDependency struct {
+	Name         string               `json:"name"`
+	Alias        string               `json:"alias,omitempty"`
+	Version      string               `json:"version,omitempty"`
+	ChartVersion string               `json:"chartVersion,omitempty"`
+	Repository   string               `json:"repository,omitempty"`
+	Condition    string               `json:"condition,omitempty"`
+	Tags         []string             `json:"tags,omitempty"`
+	Path         string               `json:"path"`
+	Enabled      bool                 `json:"enabled"`
+	Reason       string               `json:"reason,omitempty"`
+	Warnings     []string             `json:"warnings,omitempty"`
+	Missing      bool                 `json:"missing,omitempty"`
+	ImportValues []apiDataImportValue `json:"importValues,omitempty"`
+	Dependencies []apiDataDependency  `json:"dependencies,omitempty"`
+}
+
+// apiDataImportValue is an "import-values" mapping of a child value path to a parent value path.
+type apiDataImportValue struct {
+	Child  string `json:"child"`
+	Parent string `json:"parent"`
+}
+
+// apiDataValueSource is a leaf of the full values with the source and line that set it, and the values it overrode
+// in merge order. Values are formatted as JSON, and FullValuesLine is the line of the leaf in the full values.
+type apiDataValueSource struct {
+	Path           string                 `json:"path"`
+	Value          string                 `json:"value"`
+	Source         string                 `json:"source,omitempty"`
+	Line           int                    `json:"line,omitempty"`
+	FullValuesLine int                    `json:"fullValuesLine,omitempty"`
+	Overrides      []apiDataValueOverride `json:"overrides,omitempty"`
+}
+
+// apiDataValueOverride is a value set by a source that was overridden by a later one.
+type apiDataValueOverride struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Line   int    `json:"line,omitempty"`
+}
+
+// apiDataLookup is a call to the "lookup" template function, answered from the lookup fixtures. An empty Name
+// is a list request, and Result holds the returned object or list as YAML.
+type apiDataLookup struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Found      bool   `json:"found"`
+	Result     string `json:"result,omitempty"`
 }
 
 type apiDataFile struct {
 	Filename string `json:"filename"`
 	Preview  string `json:"preview"`
 }
+
+// apiDataObject is a single Kubernetes object from a rendered template file. Template is the template name shown to
+// the user, and Source its full name, like "mychart/templates/cm.yaml", used by Helm in the "# Source:" comments.
+type apiDataObject struct {
+	APIVersion   string               `json:"apiVersion"`
+	Kind         string               `json:"kind"`
+	Name         string               `json:"name"`
+	Namespace    string               `json:"namespace,omitempty"`
+	Template     string               `json:"template"`
+	Source       string               `json:"source,omitempty"`
+	Hooks        []string             `json:"hooks,omitempty"`
+	Content      string               `json:"content"`
+	Schema       string               `json:"schema,omitempty"`
+	SchemaErrors []apiDataSchemaError `json:"schemaErrors,omitempty"`
+}
+
+// apiDataPostRender is the result of the post-renderer. Input is what was sent to it, and Objects are parsed from
+// its output.
+type apiDataPostRender struct {
+	Command string          `json:"command"`
+	Input   string          `json:"input"`
+	Output  string          `json:"output"`
+	Diff    string          `json:"diff,omitempty"`
+	Objects []apiDataObject `json:"objects"`
+}
+
+// apiEnvironmentRequest is sent by the browser to switch the environment.
+type apiEnvironmentRequest struct {
+	Environment string `json:"environment"`
+}
+
+// apiDataChartVersion is a version of the chart available in the repository.
+type apiDataChartVersion struct {
+	Version string `json:"version"`
+	Created string `json:"created,omitempty"`
+}
+
+// apiChartVersionRequest is sent by the browser to switch the chart version.
+type apiChartVersionRequest struct {
+	Version string `json:"version"`
+}
+
+// apiDataDiff is the difference between the objects rendered with two sets of values or chart versions.
+// DefaultValues is an unified diff of the "values.yaml" of the chart versions.
+type apiDataDiff struct {
+	Left                 string               `json:"left"`
+	Right                string               `json:"right"`
+	Objects              []apiDataObjectDiff  `json:"objects"`
+	DefaultValues        string               `json:"defaultValues,omitempty"`
+	DefaultValuesChanges []apiDataValueChange `json:"defaultValuesChanges,omitempty"`
+	Error                *apiDataError        `json:"error,omitempty"`
+}
+
+// apiDataValueChange is a default value of the chart that was added, removed or changed between chart versions.
+// The values are formatted as JSON.
+type apiDataValueChange struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Left   string `json:"left,omitempty"`
+	Right  string `json:"right,omitempty"`
+}
+
+// apiDataObjectDiff is an object matched by kind, namespace and name. Status is "added" or "removed" if the object
+// exists only in the right or left side, and Diff is an unified diff if the object changed.
+type apiDataObjectDiff struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	Status     string `json:"status"`
+	Left       string `json:"left,omitempty"`
+	Right      string `json:"right,omitempty"`
+	Diff       string `json:"diff,omitempty"`
+}
+
+// apiDataSchemaError is a violation of the Kubernetes schema of an object.
+type apiDataSchemaError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// apiDataHookEvent is a hook event with its hooks, in the order Helm executes them.
+type apiDataHookEvent struct {
+	Event string        `json:"event"`
+	Hooks []apiDataHook `json:"hooks"`
+}
+
+// apiDataHook is a hook object. DefaultDeletePolicy is set when the object has no delete policy annotation and
+// Helm's default is used.
+type apiDataHook struct {
+	Kind                string   `json:"kind"`
+	Name                string   `json:"name"`
+	Template            string   `json:"template"`
+	Weight              int      `json:"weight"`
+	DeletePolicies      []string `json:"deletePolicies"`
+	DefaultDeletePolicy bool     `json:"defaultDeletePolicy,omitempty"`
+	Content             string   `json:"content"`
+}
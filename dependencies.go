@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rrgmc/helm-render-ui/helm"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// chartDependencies builds the dependencies missing from the "charts" folder of the charts, reusing the built ones
+// while "Chart.yaml" and "Chart.lock" don't change.
+type chartDependencies struct {
+	options []helm.RepositoryOption
+
+	mu     sync.Mutex
+	charts map[string]*builtDependencies
+}
+
+type builtDependencies struct {
+	key      string
+	files    *helm.DependencyFiles
+	archives []string
+}
+
+func newChartDependencies(options ...helm.RepositoryOption) *chartDependencies {
+	return &chartDependencies{
+		options: options,
+		charts:  map[string]*builtDependencies{},
+	}
+}
+
+// Load loads the built dependencies of the chart folder, building them if needed.
+func (d *chartDependencies) Load(chartFolder string) ([]*chart.Chart, error) {
+	if d == nil {
+		return nil, nil
+	}
+
+	key, err := dependenciesKey(chartFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	built, ok := d.charts[chartFolder]
+	if !ok || built.key != key {
+		files, err := helm.BuildDependencies(chartFolder, d.options...)
+		if err != nil {
+			return nil, err
+		}
+		if ok && built.files != nil {
+			if err := built.files.Close(); err != nil {
+				slog.Warn("error removing chart dependencies", "error", err)
+			}
+		}
+		built = &builtDependencies{
+			key:   key,
+			files: files,
+		}
+		if files != nil {
+			if files.LockOutOfDate {
+				slog.Warn("Chart.lock is out of sync with the dependencies of Chart.yaml, resolving the versions from Chart.yaml",
+					"chart", chartFolder)
+			}
+			built.archives, err = files.Archives()
+			if err != nil {
+				return nil, err
+			}
+			slog.Info("chart dependencies loaded", "chart", chartFolder, "dependencies", len(built.archives))
+		}
+		d.charts[chartFolder] = built
+	}
+
+	var ret []*chart.Chart
+	for _, archive := range built.archives {
+		dep, err := loader.LoadFile(archive)
+		if err != nil {
+			return nil, fmt.Errorf("error loading dependency %s: %w", filepath.Base(archive), err)
+		}
+		ret = append(ret, dep)
+	}
+	return ret, nil
+}
+
+// Close removes the built dependencies.
+func (d *chartDependencies) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var errs []error
+	for _, built := range d.charts {
+		if built.files != nil {
+			errs = append(errs, built.files.Close())
+		}
+	}
+	d.charts = map[string]*builtDependencies{}
+	return errors.Join(errs...)
+}
+
+// dependenciesKey returns a hash of "Chart.yaml" and "Chart.lock".
+func dependenciesKey(chartFolder string) (string, error) {
+	h := sha256.New()
+	for _, filename := range []string{chartutil.ChartfileName, "Chart.lock"} {
+		content, err := os.ReadFile(filepath.Join(chartFolder, filename))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		h.Write(content)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// dependencyTree returns the dependency tree of the chart, evaluating the tags and conditions of the dependencies
+// like chartutil.ProcessDependencies, which removes the disabled ones, so it must be called before it. Path is the
+// values path of the chart, and the dependencies of a disabled chart are shown as disabled without being evaluated.
+func dependencyTree(c *chart.Chart, values map[string]any, path string, enabled bool) ([]apiDataDependency, error) {
+	if c.Metadata == nil {
+		return nil, nil
+	}
+
+	// the subcharts are renamed to their aliases before coalescing the values, like ProcessDependencies does. Copies
+	// are used so the loaded chart isn't changed.
+	subcharts := make([]*chart.Chart, len(c.Metadata.Dependencies))
+	var unlisted, aliased []*chart.Chart
+	for _, existing := range c.Dependencies() {
+		if !slices.ContainsFunc(c.Metadata.Dependencies, func(req *chart.Dependency) bool {
+			return isDependencyChart(req, existing)
+		}) {
+			subchart := *existing
+			unlisted = append(unlisted, &subchart)
+		}
+	}
+	for idx, req := range c.Metadata.Dependencies {
+		for _, existing := range c.Dependencies() {
+			if !isDependencyChart(req, existing) {
+				continue
+			}
+			subchart := *existing
+			metadata := *existing.Metadata
+			if req.Alias != "" {
+				metadata.Name = req.Alias
+			}
+			subchart.Metadata = &metadata
+			subcharts[idx] = &subchart
+			aliased = append(aliased, &subchart)
+			break
+		}
+	}
+
+	var cvals chartutil.Values
+	if enabled {
+		aliasedChart := *c
+		aliasedChart.SetDependencies(slices.Concat(unlisted, aliased)...)
+		var err error
+		cvals, err = chartutil.CoalesceValues(&aliasedChart, values)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ret []apiDataDependency
+	for idx, req := range c.Metadata.Dependencies {
+		if req == nil {
+			continue
+		}
+		dep := apiDataDependency{
+			Name:         req.Name,
+			Alias:        req.Alias,
+			Version:      req.Version,
+			Repository:   req.Repository,
+			Condition:    req.Condition,
+			Tags:         req.Tags,
+			Path:         path + cmp.Or(req.Alias, req.Name),
+			ImportValues: dependencyImportValues(req),
+		}
+		if enabled {
+			dep.Enabled, dep.Reason, dep.Warnings = dependencyEnabled(req, cvals, path)
+		} else {
+			dep.Reason = "parent chart is disabled"
+		}
+		if subchart := subcharts[idx]; subchart != nil {
+			dep.ChartVersion = subchart.Metadata.Version
+			var err error
+			dep.Dependencies, err = dependencyTree(subchart, cvals, dep.Path+".", dep.Enabled)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			dep.Missing = true
+		}
+		ret = append(ret, dep)
+	}
+
+	// charts in the "charts" folder that don't match a dependency of "Chart.yaml" are always rendered.
+	for _, subchart := range unlisted {
+		dep := apiDataDependency{
+			Name:         subchart.Name(),
+			ChartVersion: subchart.Metadata.Version,
+			Path:         path + subchart.Name(),
+			Enabled:      enabled,
+			Reason:       "not a dependency in Chart.yaml",
+		}
+		if !enabled {
+			dep.Reason = "parent chart is disabled"
+		}
+		var err error
+		dep.Dependencies, err = dependencyTree(subchart, cvals, dep.Path+".", enabled)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, dep)
+	}
+	return ret, nil
+}
+
+// isDependencyChart returns whether the subchart is the one loaded for the dependency, using the same rule as Helm.
+func isDependencyChart(req *chart.Dependency, subchart *chart.Chart) bool {
+	return req != nil && subchart != nil && subchart.Name() == req.Name &&
+		chartutil.IsCompatibleRange(req.Version, subchart.Metadata.Version)
+}
+
+// dependencyEnabled evaluates the tags and then the condition of the dependency, which takes precedence, returning
+// whether it is enabled and which tag or condition decided it. The reason is empty if none was set in the values.
+func dependencyEnabled(req *chart.Dependency, cvals chartutil.Values, path string) (bool, string, []string) {
+	enabled, reason := true, ""
+	var warnings []string
+
+	tags, _ := cvals.Table("tags")
+	var trueTags, falseTags []string
+	for _, tag := range req.Tags {
+		value, ok := tags[tag]
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case bool:
+			if v {
+				trueTags = append(trueTags, tag+"=true")
+			} else {
+				falseTags = append(falseTags, tag+"=false")
+			}
+		default:
+			warnings = append(warnings, fmt.Sprintf("tag %s is not a boolean", tag))
+		}
+	}
+	// any true tag enables the dependency.
+	if len(trueTags) > 0 {
+		reason = "tag " + strings.Join(trueTags, ", ")
+	} else if len(falseTags) > 0 {
+		enabled, reason = false, "tag "+strings.Join(falseTags, ", ")
+	}
+
+	// the first condition path that is set decides.
+	for _, condition := range strings.Split(strings.TrimSpace(req.Condition), ",") {
+		if condition == "" {
+			continue
+		}
+		value, err := cvals.PathValue(path + condition)
+		if err != nil {
+			continue
+		}
+		if v, ok := value.(bool); ok {
+			return v, fmt.Sprintf("condition %s%s=%t", path, condition, v), warnings
+		}
+		warnings = append(warnings, fmt.Sprintf("condition %s%s is not a boolean", path, condition))
+	}
+	return enabled, reason, warnings
+}
+
+// dependencyImportValues returns the "import-values" of the dependency. The string form imports the child
+// "exports" value into the parent values root.
+func dependencyImportValues(req *chart.Dependency) []apiDataImportValue {
+	var ret []apiDataImportValue
+	for _, importValue := range req.ImportValues {
+		switch iv := importValue.(type) {
+		case map[string]any:
+			ret = append(ret, apiDataImportValue{
+				Child:  fmt.Sprintf("%v", iv["child"]),
+				Parent: fmt.Sprintf("%v", iv["parent"]),
+			})
+		case string:
+			ret = append(ret, apiDataImportValue{
+				Child:  "exports." + iv,
+				Parent: ".",
+			})
+		}
+	}
+	return ret
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	diffStatusAdded     = "added"
+	diffStatusRemoved   = "removed"
+	diffStatusChanged   = "changed"
+	diffStatusUnchanged = "unchanged"
+)
+
+// isCompareEnabled returns whether compare values or a compare chart version were set.
+func isCompareEnabled(options renderOptions) bool {
+	return options.CompareValues != nil || options.CompareChartFolder != ""
+}
+
+// compareChart renders the chart with the values and with the compared values and/or chart version, and returns the
+// difference between the objects of both. When comparing chart versions, the default values of the charts are also
+// compared.
+func compareChart(options renderOptions) (apiDataDiff, error) {
+	if !isCompareEnabled(options) {
+		return apiDataDiff{}, fmt.Errorf("compare mode is not enabled")
+	}
+
+	rightOptions := options
+	if options.CompareValues != nil {
+		rightOptions.Values = *options.CompareValues
+	}
+	if options.CompareChartFolder != "" {
+		rightOptions.ChartFolder = options.CompareChartFolder
+		rightOptions.ChartVersion = options.CompareChartVersion
+	}
+
+	ret := apiDataDiff{
+		Left:  compareLabel(options, rightOptions),
+		Right: compareLabel(rightOptions, options),
+	}
+
+	if options.CompareChartFolder != "" {
+		var err error
+		ret.DefaultValues, ret.DefaultValuesChanges, err = diffDefaultValues(options, rightOptions)
+		if err != nil {
+			ret.Error = apiDataErrorFromError(err)
+			return ret, nil
+		}
+	}
+
+	left, err := renderChart(options)
+	if err != nil {
+		ret.Error = apiDataErrorFromError(err)
+		return ret, nil
+	}
+	right, err := renderChart(rightOptions)
+	if err != nil {
+		ret.Error = apiDataErrorFromError(fmt.Errorf("error rendering compared values: %w", err))
+		return ret, nil
+	}
+
+	ret.Objects = diffObjects(ret.Left, ret.Right, left.Objects, right.Objects)
+	return ret, nil
+}
+
+// diffObjects matches the objects by kind, namespace and name, and returns an unified diff of each pair. Objects
+// existing in only one side are returned as added or removed.
+func diffObjects(leftLabel, rightLabel string, left, right []apiDataObject) []apiDataObjectDiff {
+	rightByKey := map[string][]apiDataObject{}
+	for _, obj := range right {
+		rightByKey[objectKey(obj)] = append(rightByKey[objectKey(obj)], obj)
+	}
+
+	var ret []apiDataObjectDiff
+	matched := map[string]int{}
+	for _, leftObj := range left {
+		key := objectKey(leftObj)
+		item := newObjectDiff(leftObj)
+		item.Left = leftObj.Content
+		if rightObjs := rightByKey[key]; matched[key] < len(rightObjs) {
+			item.Right = rightObjs[matched[key]].Content
+			matched[key]++
+			item.Status = diffStatusUnchanged
+			if item.Left != item.Right {
+				item.Status = diffStatusChanged
+				item.Diff = unifiedDiff(leftLabel, rightLabel, item.Left, item.Right)
+			}
+		} else {
+			item.Status = diffStatusRemoved
+		}
+		ret = append(ret, item)
+	}
+
+	seen := map[string]int{}
+	for _, rightObj := range right {
+		key := objectKey(rightObj)
+		seen[key]++
+		if seen[key] <= matched[key] {
+			continue
+		}
+		item := newObjectDiff(rightObj)
+		item.Right = rightObj.Content
+		item.Status = diffStatusAdded
+		ret = append(ret, item)
+	}
+
+	return ret
+}
+
+func newObjectDiff(obj apiDataObject) apiDataObjectDiff {
+	return apiDataObjectDiff{
+		APIVersion: obj.APIVersion,
+		Kind:       obj.Kind,
+		Name:       obj.Name,
+		Namespace:  obj.Namespace,
+	}
+}
+
+// objectKey identifies the object across renders. The API version is not part of it, so changing the version of
+// a kind is shown as a change.
+func objectKey(obj apiDataObject) string {
+	return strings.Join([]string{obj.Kind, obj.Namespace, obj.Name}, "/")
+}
+
+func unifiedDiff(leftLabel, rightLabel, left, right string) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(left),
+		B:        difflib.SplitLines(right),
+		FromFile: leftLabel,
+		ToFile:   rightLabel,
+		Context:  3,
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return diff
+}
+
+// compareLabel describes one side of the comparison, including the chart version if it differs from the other
+// side.
+func compareLabel(options renderOptions, other renderOptions) string {
+	label := valuesLabel(options)
+	if options.ChartVersion != other.ChartVersion {
+		label = fmt.Sprintf("chart %s: %s", options.ChartVersion, label)
+	}
+	return label
+}
+
+// diffDefaultValues returns an unified diff of the "values.yaml" of the charts, and the values that were added,
+// removed or changed.
+func diffDefaultValues(left, right renderOptions) (string, []apiDataValueChange, error) {
+	leftContent, leftValues, err := readDefaultValues(left.ChartFolder)
+	if err != nil {
+		return "", nil, err
+	}
+	rightContent, rightValues, err := readDefaultValues(right.ChartFolder)
+	if err != nil {
+		return "", nil, err
+	}
+
+	diff := unifiedDiff(
+		fmt.Sprintf("%s/%s", left.ChartVersion, chartutil.ValuesfileName),
+		fmt.Sprintf("%s/%s", right.ChartVersion, chartutil.ValuesfileName),
+		leftContent, rightContent)
+
+	leftFlat, rightFlat := map[string]string{}, map[string]string{}
+	flattenValues(nil, leftValues, leftFlat)
+	flattenValues(nil, rightValues, rightFlat)
+
+	var changes []apiDataValueChange
+	for valuePath, leftValue := range mapSortedByKey(leftFlat) {
+		rightValue, ok := rightFlat[valuePath]
+		switch {
+		case !ok:
+			changes = append(changes, apiDataValueChange{Path: valuePath, Status: diffStatusRemoved, Left: leftValue})
+		case rightValue != leftValue:
+			changes = append(changes, apiDataValueChange{Path: valuePath, Status: diffStatusChanged, Left: leftValue, Right: rightValue})
+		}
+	}
+	for valuePath, rightValue := range mapSortedByKey(rightFlat) {
+		if _, ok := leftFlat[valuePath]; !ok {
+			changes = append(changes, apiDataValueChange{Path: valuePath, Status: diffStatusAdded, Right: rightValue})
+		}
+	}
+	slices.SortStableFunc(changes, func(a, b apiDataValueChange) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+
+	return diff, changes, nil
+}
+
+func readDefaultValues(chartFolder string) (string, map[string]any, error) {
+	content, err := os.ReadFile(filepath.Join(chartFolder, chartutil.ValuesfileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", map[string]any{}, nil
+		}
+		return "", nil, err
+	}
+	values := map[string]any{}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return "", nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(chartFolder, chartutil.ValuesfileName), err)
+	}
+	return string(content), values, nil
+}
+
+// flattenValues sets the leaf values in ret, keyed by their path. Lists and empty maps are leafs, formatted as
+// JSON.
+func flattenValues(prefix []string, value any, ret map[string]string) {
+	if m, ok := value.(map[string]any); ok && len(m) > 0 {
+		for key, item := range m {
+			flattenValues(append(slices.Clone(prefix), key), item, ret)
+		}
+		return
+	}
+	ret[formatValuePath(prefix)] = formatValueJSON(value)
+}
+
+// formatValueJSON formats the value as JSON, or with fmt if it can't be marshaled.
+func formatValueJSON(value any) string {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(valueJSON)
+}
+
+// valuesLabel describes the value files and flags used in a render.
+func valuesLabel(options renderOptions) string {
+	label := strings.Join(append(displayValueFiles(options), displaySetValues(options)...), " ")
+	if label == "" {
+		return "(chart defaults)"
+	}
+	return label
+}
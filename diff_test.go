@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffObjects(t *testing.T) {
+	object := func(kind, name, content string) apiDataObject {
+		return apiDataObject{APIVersion: "v1", Kind: kind, Name: name, Namespace: "default", Content: content}
+	}
+	left := []apiDataObject{
+		object("ConfigMap", "same", "data: 1\n"),
+		object("ConfigMap", "changed", "data: 1\n"),
+		object("ConfigMap", "removed", "data: 1\n"),
+		object("Secret", "dup", "a\n"),
+	}
+	right := []apiDataObject{
+		object("ConfigMap", "added", "data: 2\n"),
+		object("ConfigMap", "changed", "data: 2\n"),
+		object("ConfigMap", "same", "data: 1\n"),
+		object("Secret", "dup", "a\n"),
+		object("Secret", "dup", "b\n"),
+	}
+
+	type result struct {
+		Kind, Name, Status string
+		HasDiff            bool
+	}
+	var got []result
+	for _, item := range diffObjects("left", "right", left, right) {
+		got = append(got, result{Kind: item.Kind, Name: item.Name, Status: item.Status, HasDiff: item.Diff != ""})
+	}
+	want := []result{
+		{Kind: "ConfigMap", Name: "same", Status: diffStatusUnchanged},
+		{Kind: "ConfigMap", Name: "changed", Status: diffStatusChanged, HasDiff: true},
+		{Kind: "ConfigMap", Name: "removed", Status: diffStatusRemoved},
+		// objects with the same key are matched in order.
+		{Kind: "Secret", Name: "dup", Status: diffStatusUnchanged},
+		{Kind: "ConfigMap", Name: "added", Status: diffStatusAdded},
+		{Kind: "Secret", Name: "dup", Status: diffStatusAdded},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestDiffObjectsContent(t *testing.T) {
+	left := []apiDataObject{{APIVersion: "v1", Kind: "ConfigMap", Name: "cm", Content: "data:\n  a: 1\n"}}
+	right := []apiDataObject{{APIVersion: "v1", Kind: "ConfigMap", Name: "cm", Content: "data:\n  a: 2\n"}}
+
+	got := diffObjects("values.yaml", "prod.yaml", left, right)
+	want := []apiDataObjectDiff{{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Name:       "cm",
+		Status:     diffStatusChanged,
+		Left:       "data:\n  a: 1\n",
+		Right:      "data:\n  a: 2\n",
+		// difflib splits the final newline as an empty line.
+		Diff: "--- values.yaml\n+++ prod.yaml\n@@ -1,3 +1,3 @@\n data:\n-  a: 1\n+  a: 2\n \n",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
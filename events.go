@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// eventBroker fans out server-sent events to all connected browsers.
+type eventBroker struct {
+	mu      sync.Mutex
+	clients map[chan string]struct{}
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		clients: map[chan string]struct{}{},
+	}
+}
+
+// Publish sends the event to all connected clients. Clients that are not keeping up miss the event.
+func (b *eventBroker) Publish(event string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for client := range b.clients {
+		select {
+		case client <- event:
+		default:
+		}
+	}
+}
+
+func (b *eventBroker) subscribe() chan string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	client := make(chan string, 1)
+	b.clients[client] = struct{}{}
+	return client
+}
+
+func (b *eventBroker) unsubscribe(client chan string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.clients, client)
+}
+
+func (b *eventBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", devUIOrigin)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	client := b.subscribe()
+	defer b.unsubscribe(client)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-client:
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: {}\n\n", event); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
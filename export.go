@@ -0,0 +1,202 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// exportDataVariable is the global variable where the exported page keeps the data, read by the UI instead of
+// calling the server.
+const exportDataVariable = "__HELM_RENDER_UI_DATA__"
+
+var (
+	exportStylesheetRE = regexp.MustCompile(`<link href="/(static/css/[^"]+)" rel="stylesheet">`)
+	exportScriptRE     = regexp.MustCompile(`<script src="/(static/js/[^"]+)"></script>`)
+	exportSourceMapRE  = regexp.MustCompile(`(?m)^(//|/\*)# sourceMappingURL=.*$`)
+	// the web font, which is not exported, so the page has no external requests.
+	exportExternalStylesheetRE = regexp.MustCompile(`<link href="https?://[^"]+" rel="stylesheet">`)
+	// the icon and manifest links.
+	exportLinkRE = regexp.MustCompile(`<link rel="([^"]+)" href="/([^"]+)"/?>`)
+	// the paths from the server root.
+	exportRootPathRE = regexp.MustCompile(`(href|src)="/([^/"][^"]*)"`)
+)
+
+func exportCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "export",
+		Usage:     "render the chart and write the UI with the rendered data, to be opened without a server",
+		ArgsUsage: "[helm chart folder]",
+		Arguments: chartArguments(),
+		Flags: append(renderFlags(),
+			&cli.StringFlag{
+				Name:     "output",
+				Aliases:  []string{"o"},
+				Usage:    "output folder, or a single HTML file if it ends with '.html'",
+				Required: true,
+			},
+		),
+		Action: func(ctx context.Context, command *cli.Command) error {
+			options, _, closeOptions, err := loadRenderOptions(ctx, command, command.String("env"))
+			if err != nil {
+				return err
+			}
+			defer closeOptions()
+
+			data, err := renderChart(options)
+			if err != nil {
+				return err
+			}
+			export := apiExport{
+				Data: data,
+			}
+			if isCompareEnabled(options) {
+				diff, err := compareChart(options)
+				if err != nil {
+					return err
+				}
+				export.Diff = &diff
+			}
+
+			output := command.String("output")
+			if strings.EqualFold(filepath.Ext(output), ".html") {
+				err = exportHTMLFile(output, export)
+			} else {
+				err = exportFolder(output, export)
+			}
+			if err != nil {
+				return fmt.Errorf("error exporting to %s: %w", output, err)
+			}
+			slog.InfoContext(ctx, "render exported", "output", output)
+			return nil
+		},
+	}
+}
+
+// exportFolder writes the UI files to the folder, with the data added to "index.html".
+func exportFolder(folder string, export apiExport) error {
+	zipReader, err := zip.NewReader(bytes.NewReader(staticzipFS), int64(len(staticzipFS)))
+	if err != nil {
+		return err
+	}
+
+	return fs.WalkDir(zipReader, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid file name in UI archive: %s", name)
+		}
+
+		content, err := fs.ReadFile(zipReader, name)
+		if err != nil {
+			return err
+		}
+		if name == "index.html" {
+			content, err = exportIndex(content, export, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		filename := filepath.Join(folder, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(filename, content, 0o644)
+	})
+}
+
+// exportHTMLFile writes a single HTML file, with the stylesheets, scripts and data inlined.
+func exportHTMLFile(filename string, export apiExport) error {
+	zipReader, err := zip.NewReader(bytes.NewReader(staticzipFS), int64(len(staticzipFS)))
+	if err != nil {
+		return err
+	}
+
+	index, err := fs.ReadFile(zipReader, "index.html")
+	if err != nil {
+		return err
+	}
+
+	content, err := exportIndex(index, export, zipReader)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, content, 0o644)
+}
+
+// exportIndex adds the data to "index.html" and makes the file paths relative. If inline is set, the stylesheets,
+// scripts and icons are read from it and added to the page, and the manifest is removed. The external web font is
+// removed, so the page works offline.
+func exportIndex(index []byte, export apiExport, inline fs.FS) ([]byte, error) {
+	// json.Marshal escapes "<", ">" and "&", so the data can't close the script tag.
+	exportJSON, err := json.Marshal(export)
+	if err != nil {
+		return nil, err
+	}
+
+	page := string(index)
+	page = exportExternalStylesheetRE.ReplaceAllString(page, "")
+	page = strings.Replace(page, "</head>",
+		fmt.Sprintf("<script>window.%s=%s;</script></head>", exportDataVariable, exportJSON), 1)
+
+	var inlineErr error
+	readFile := func(name string) []byte {
+		content, err := fs.ReadFile(inline, path.Clean(name))
+		if err != nil {
+			inlineErr = err
+		}
+		return content
+	}
+	inlineFile := func(name string) string {
+		return exportSourceMapRE.ReplaceAllString(string(readFile(name)), "")
+	}
+
+	if inline != nil {
+		page = exportStylesheetRE.ReplaceAllStringFunc(page, func(tag string) string {
+			name := exportStylesheetRE.FindStringSubmatch(tag)[1]
+			return "<style>" + strings.ReplaceAll(inlineFile(name), "</style", `<\/style`) + "</style>"
+		})
+		page = exportScriptRE.ReplaceAllStringFunc(page, func(tag string) string {
+			name := exportScriptRE.FindStringSubmatch(tag)[1]
+			return "<script>" + strings.ReplaceAll(inlineFile(name), "</script", `<\/script`) + "</script>"
+		})
+		page = exportLinkRE.ReplaceAllStringFunc(page, func(tag string) string {
+			m := exportLinkRE.FindStringSubmatch(tag)
+			if m[1] == "manifest" {
+				return ""
+			}
+			dataURL := "data:" + mime.TypeByExtension(path.Ext(m[2])) + ";base64," +
+				base64.StdEncoding.EncodeToString(readFile(m[2]))
+			return fmt.Sprintf(`<link rel="%s" href="%s"/>`, m[1], dataURL)
+		})
+		if inlineErr != nil {
+			return nil, inlineErr
+		}
+	} else {
+		page = exportRootPathRE.ReplaceAllString(page, `$1="$2"`)
+	}
+
+	return []byte(page), nil
+}
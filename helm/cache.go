@@ -0,0 +1,288 @@
+package helm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const (
+	CacheKindIndex        = "index"
+	CacheKindChart        = "chart"
+	CacheKindDependencies = "dependencies"
+)
+
+const (
+	cacheIndexFolder      = "indexes"
+	cacheChartFolder      = "charts"
+	cacheDependencyFolder = "dependencies"
+)
+
+// Cache is a persistent cache of repository index files and chart archives. Index files are keyed by the repository
+// URL and expire after the index TTL, and chart archives are keyed by the repository URL, name, version and digest.
+// The dependencies built for a chart are keyed by the chart name and the locked dependencies.
+type Cache struct {
+	dir      string
+	indexTTL time.Duration
+	offline  bool
+}
+
+// CacheEntry is an index file, chart archive or chart dependencies folder in the cache.
+type CacheEntry struct {
+	Kind       string `json:"kind"`
+	Repository string `json:"repository,omitempty"`
+	Chart      string `json:"chart,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+
+	Size     int64     `json:"-"`
+	Modified time.Time `json:"-"`
+
+	key    string
+	folder string
+}
+
+func NewCache(dir string, options ...CacheOption) *Cache {
+	ret := &Cache{
+		dir: dir,
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Offline returns whether everything must be loaded from the cache.
+func (c *Cache) Offline() bool {
+	return c.offline
+}
+
+// Entries returns the entries in the cache.
+func (c *Cache) Entries() ([]CacheEntry, error) {
+	var ret []CacheEntry
+	for _, folder := range []string{cacheIndexFolder, cacheChartFolder, cacheDependencyFolder} {
+		metadataFiles, err := filepath.Glob(filepath.Join(c.dir, folder, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, metadataFile := range metadataFiles {
+			entry, err := c.readEntry(metadataFile)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, entry)
+		}
+	}
+	return ret, nil
+}
+
+// Prune removes the entries not modified in the duration, or all the entries if it is zero, and returns the removed
+// entries.
+func (c *Cache) Prune(olderThan time.Duration) ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var ret []CacheEntry
+	for _, entry := range entries {
+		if olderThan > 0 && time.Since(entry.Modified) < olderThan {
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(entry.folder, entry.key+"*"))
+		if err != nil {
+			return ret, err
+		}
+		for _, file := range files {
+			if err := os.RemoveAll(file); err != nil {
+				return ret, err
+			}
+		}
+		ret = append(ret, entry)
+	}
+	return ret, nil
+}
+
+// repositoryIndex returns the cached index file of the repository, downloading it if missing or expired. The
+// repository is changed to download the index to the cache.
+func (c *Cache) repositoryIndex(repository *repo.ChartRepository) (string, error) {
+	key := cacheKey(repository.Config.URL)
+	repository.Config.Name = key
+	repository.CachePath = filepath.Join(c.dir, cacheIndexFolder)
+
+	indexFilename := filepath.Join(repository.CachePath, helmpath.CacheIndexFile(key))
+	if st, err := os.Stat(indexFilename); err == nil && (c.offline || time.Since(st.ModTime()) < c.indexTTL) {
+		return indexFilename, nil
+	}
+	if c.offline {
+		return "", fmt.Errorf("index of repository %s is not cached", repository.Config.URL)
+	}
+
+	indexFilename, err := repository.DownloadIndexFile()
+	if err != nil {
+		return "", err
+	}
+	err = c.writeEntry(repository.CachePath, key, CacheEntry{
+		Kind:       CacheKindIndex,
+		Repository: repository.Config.URL,
+	})
+	if err != nil {
+		return "", err
+	}
+	return indexFilename, nil
+}
+
+// chartArchive returns the cached chart archive, calling download to download it to a folder if missing. A cached
+// archive that doesn't match the digest of the index file is downloaded again.
+func (c *Cache) chartArchive(repoURL string, chart *repo.ChartVersion, download func(dest string) (string, error)) (string, error) {
+	if chart.Version == "" {
+		return "", fmt.Errorf("a chart version is required to load chart %s from the cache", chart.Name)
+	}
+
+	folder := filepath.Join(c.dir, cacheChartFolder)
+	key := cacheKey(repoURL, chart.Name, chart.Version, chart.Digest)
+	filename := filepath.Join(folder, key+".tgz")
+	if _, err := os.Stat(filename); err == nil {
+		valid, err := archiveMatchesDigest(filename, chart.Digest)
+		if err != nil {
+			return "", err
+		}
+		if valid {
+			now := time.Now()
+			_ = os.Chtimes(filename, now, now)
+			return filename, nil
+		}
+		if c.offline {
+			return "", fmt.Errorf("cached chart %s version %s doesn't match the digest %s", chart.Name, chart.Version,
+				chart.Digest)
+		}
+		if err := os.Remove(filename); err != nil {
+			return "", err
+		}
+	} else if c.offline {
+		return "", fmt.Errorf("chart %s version %s is not cached", chart.Name, chart.Version)
+	}
+
+	if err := os.MkdirAll(folder, 0o755); err != nil {
+		return "", err
+	}
+	downloadPath, err := os.MkdirTemp(folder, "download")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.RemoveAll(downloadPath)
+	}()
+
+	downloadedFile, err := download(downloadPath)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(downloadedFile, filename); err != nil {
+		return "", err
+	}
+	err = c.writeEntry(folder, key, CacheEntry{
+		Kind:       CacheKindChart,
+		Repository: repoURL,
+		Chart:      chart.Name,
+		Version:    chart.Version,
+		Digest:     chart.Digest,
+	})
+	if err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// archiveMatchesDigest returns whether the sha256 digest of the archive is the one of the index file, if set.
+func archiveMatchesDigest(filename string, digest string) (bool, error) {
+	if digest == "" {
+		return true, nil
+	}
+	fileDigest, err := provenance.DigestFile(filename)
+	if err != nil {
+		return false, err
+	}
+	return fileDigest == strings.TrimPrefix(digest, "sha256:"), nil
+}
+
+func (c *Cache) writeEntry(folder string, key string, entry CacheEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(folder, key+".json"), content, 0o644)
+}
+
+func (c *Cache) readEntry(metadataFile string) (CacheEntry, error) {
+	content, err := os.ReadFile(metadataFile)
+	if err != nil {
+		return CacheEntry{}, err
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return CacheEntry{}, fmt.Errorf("error reading cache entry %s: %w", metadataFile, err)
+	}
+	entry.folder = filepath.Dir(metadataFile)
+	entry.key = strings.TrimSuffix(filepath.Base(metadataFile), ".json")
+
+	dataFile := filepath.Join(entry.folder, entry.key+".tgz")
+	switch entry.Kind {
+	case CacheKindIndex:
+		dataFile = filepath.Join(entry.folder, helmpath.CacheIndexFile(entry.key))
+	case CacheKindDependencies:
+		dataFile = filepath.Join(entry.folder, entry.key)
+	}
+	if st, err := os.Stat(dataFile); err == nil {
+		entry.Size = st.Size()
+		entry.Modified = st.ModTime()
+	}
+	if entry.Kind == CacheKindDependencies {
+		// the size of the archives in the folder.
+		entry.Size = 0
+		archives, _ := filepath.Glob(filepath.Join(dataFile, "*.tgz"))
+		for _, archive := range archives {
+			if st, err := os.Stat(archive); err == nil {
+				entry.Size += st.Size()
+			}
+		}
+	}
+	return entry, nil
+}
+
+func cacheKey(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// WithCacheIndexTTL sets how long the repository index files are used before being downloaded again.
+func WithCacheIndexTTL(indexTTL time.Duration) CacheOption {
+	return func(cache *Cache) {
+		cache.indexTTL = indexTTL
+	}
+}
+
+// WithCacheOffline loads everything from the cache, failing if not cached.
+func WithCacheOffline(offline bool) CacheOption {
+	return func(cache *Cache) {
+		cache.offline = offline
+	}
+}
+
+type CacheOption func(*Cache)
@@ -0,0 +1,151 @@
+package helm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestCacheChartArchiveDigest(t *testing.T) {
+	content := []byte("chart archive")
+	sum := sha256.Sum256(content)
+	chartVersion := &repo.ChartVersion{
+		Metadata: &chart.Metadata{Name: "web", Version: "1.0.0"},
+		Digest:   hex.EncodeToString(sum[:]),
+	}
+
+	downloads := 0
+	download := func(dest string) (string, error) {
+		downloads++
+		filename := filepath.Join(dest, "web-1.0.0.tgz")
+		return filename, os.WriteFile(filename, content, 0o644)
+	}
+
+	cache := NewCache(t.TempDir())
+	filename, err := cache.chartArchive("https://charts.example.com", chartVersion, download)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.chartArchive("https://charts.example.com", chartVersion, download); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 1 {
+		t.Fatalf("expected the cached archive to be used, got %d downloads", downloads)
+	}
+
+	// a changed archive is downloaded again.
+	if err := os.WriteFile(filename, []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.chartArchive("https://charts.example.com", chartVersion, download); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 2 {
+		t.Fatalf("expected the changed archive to be downloaded again, got %d downloads", downloads)
+	}
+	if data, err := os.ReadFile(filename); err != nil || string(data) != string(content) {
+		t.Fatalf("unexpected cached archive %q (error %v)", data, err)
+	}
+
+	// offline, a changed archive is an error.
+	if err := os.WriteFile(filename, []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	offlineCache := NewCache(cache.Dir(), WithCacheOffline(true))
+	if _, err := offlineCache.chartArchive("https://charts.example.com", chartVersion, download); err == nil {
+		t.Fatal("expected an error loading a changed archive offline")
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	if cacheKey("a", "bc") != cacheKey("a", "bc") {
+		t.Fatal("expected the same key for the same parts")
+	}
+	// the parts are separated, so moving characters between them changes the key.
+	if cacheKey("a", "bc") == cacheKey("ab", "c") {
+		t.Fatal("expected different keys for different parts")
+	}
+	if cacheKey("a", "") == cacheKey("a") {
+		t.Fatal("expected an empty part to change the key")
+	}
+	if key := cacheKey("https://charts.example.com"); len(key) != sha256.Size*2 {
+		t.Fatalf("expected an hex sha256 key, got %q", key)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	cache := NewCache(t.TempDir())
+	addChart := func(version string, modified time.Time) {
+		chartVersion := &repo.ChartVersion{
+			Metadata: &chart.Metadata{Name: "web", Version: version},
+		}
+		filename, err := cache.chartArchive("https://charts.example.com", chartVersion, func(dest string) (string, error) {
+			filename := filepath.Join(dest, "web-"+version+".tgz")
+			return filename, os.WriteFile(filename, []byte(version), 0o644)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addChart("1.0.0", time.Now().Add(-48*time.Hour))
+	addChart("1.1.0", time.Now())
+
+	entryVersions := func(entries []CacheEntry) []string {
+		var ret []string
+		for _, entry := range entries {
+			ret = append(ret, entry.Version)
+		}
+		slices.Sort(ret)
+		return ret
+	}
+
+	entries, err := cache.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entryVersions(entries), []string{"1.0.0", "1.1.0"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected entries %v, got %v", want, got)
+	}
+
+	removed, err := cache.Prune(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entryVersions(removed), []string{"1.0.0"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected removed entries %v, got %v", want, got)
+	}
+	entries, err = cache.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entryVersions(entries), []string{"1.1.0"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected entries %v after pruning, got %v", want, got)
+	}
+	files, err := filepath.Glob(filepath.Join(cache.Dir(), cacheChartFolder, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected the archive and metadata of one chart, got %v", files)
+	}
+
+	// zero removes everything.
+	removed, err = cache.Prune(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entryVersions(removed), []string{"1.1.0"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected removed entries %v, got %v", want, got)
+	}
+}
@@ -26,6 +26,8 @@ func (c *Chart) Chart() *repo.ChartVersion {
 	return c.chart
 }
 
+// Download downloads the chart, or loads it from the cache, and expands it to the download path, or to a temporary
+// folder which is removed when the returned files are closed.
 func (c *Chart) Download(options ...ChartDownloadOption) (*ChartFiles, error) {
 	var optns chartDownloadOptions
 	for _, opt := range options {
@@ -45,39 +47,63 @@ func (c *Chart) Download(options ...ChartDownloadOption) (*ChartFiles, error) {
 		return nil, fmt.Errorf("failed to make chart URL absolute: %w", err)
 	}
 
-	isTempPath := false
-	if optns.downloadPath == "" {
-		optns.downloadPath, err = os.MkdirTemp("", "helm-chart")
+	downloadPath, isTempPath := optns.downloadPath, false
+	if downloadPath == "" {
+		downloadPath, err = os.MkdirTemp("", "helm-chart")
 		if err != nil {
 			return nil, fmt.Errorf("unable to create temporary directory for download: %w", err)
 		}
 		isTempPath = true
 	}
+	// the temporary folder is removed on errors.
+	removeTempPath := func() {
+		if isTempPath {
+			_ = os.RemoveAll(downloadPath)
+		}
+	}
 
 	dl := downloader.ChartDownloader{
 		Out:            os.Stderr,
 		Getters:        allGetters,
 		RegistryClient: c.repository.registry,
+		Options:        c.repository.getterOptions(absoluteChartURL),
 	}
 
-	chartPackageFile, _, err := dl.DownloadTo(absoluteChartURL, c.chart.Version, optns.downloadPath)
-	if err != nil {
-		return nil, fmt.Errorf("error downloading chart: %w", err)
+	download := func(dest string) (string, error) {
+		chartPackageFile, _, err := dl.DownloadTo(absoluteChartURL, c.chart.Version, dest)
+		return chartPackageFile, err
+	}
+
+	var chartPackageFile string
+	// charts without a version (the latest one of an OCI repository) are only loaded from the cache when offline.
+	if cache := c.repository.cache; cache != nil && (c.chart.Version != "" || cache.Offline()) {
+		chartPackageFile, err = cache.chartArchive(c.repository.repository.Config.URL, c.chart, download)
+		if err != nil {
+			removeTempPath()
+			return nil, fmt.Errorf("error loading chart from cache: %w", err)
+		}
+	} else {
+		chartPackageFile, err = download(downloadPath)
+		if err != nil {
+			removeTempPath()
+			return nil, fmt.Errorf("error downloading chart: %w", err)
+		}
+		defer func() {
+			_ = os.Remove(chartPackageFile)
+		}()
 	}
 
-	err = chartutil.ExpandFile(optns.downloadPath, chartPackageFile)
+	err = chartutil.ExpandFile(downloadPath, chartPackageFile)
 	if err != nil {
+		removeTempPath()
 		return nil, fmt.Errorf("error expanding chart: %w", err)
 	}
 
-	_ = os.Remove(chartPackageFile)
-	// if err != nil {
-	// 	return nil, fmt.Errorf("error removing chart temporary file: %w", err)
-	// }
-
-	return newChartFiles(c, optns.downloadPath, isTempPath)
+	return newChartFiles(c, downloadPath, isTempPath)
 }
 
+// WithChartDownloadPath expands the chart to the folder instead of a temporary one. The folder is not removed when
+// the files are closed.
 func WithChartDownloadPath(path string) ChartDownloadOption {
 	return func(options *chartDownloadOptions) {
 		options.downloadPath = path
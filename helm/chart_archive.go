@@ -0,0 +1,86 @@
+package helm
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// LoadChartArchive expands a packaged chart archive to a temporary folder, which is removed when the returned files
+// are closed.
+func LoadChartArchive(archive string) (*ChartFiles, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return expandChartArchive(f)
+}
+
+// DownloadChartArchive downloads a packaged chart archive from the URL and expands it to a temporary folder, which
+// is removed when the returned files are closed. The repository authentication and TLS options are used.
+func DownloadChartArchive(chartURL string, options ...RepositoryOption) (*ChartFiles, error) {
+	optns := repositoryOptions{
+		entry: repo.Entry{
+			URL: chartURL,
+		},
+	}
+	for _, opt := range options {
+		opt(&optns)
+	}
+
+	u, err := url.Parse(chartURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chart URL %s: %w", chartURL, err)
+	}
+	g, err := allGetters.ByScheme(u.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	data, err := g.Get(chartURL, append(entryGetterOptions(&optns.entry, chartURL),
+		getter.WithURL(chartURL),
+		getter.WithAcceptHeader("application/gzip,application/octet-stream"))...)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading chart: %w", err)
+	}
+	return expandChartArchive(data)
+}
+
+func expandChartArchive(r io.Reader) (*ChartFiles, error) {
+	path, err := os.MkdirTemp("", "helm-chart")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary directory for chart: %w", err)
+	}
+	ret := &ChartFiles{
+		path:       path,
+		isTempPath: true,
+	}
+
+	if err := chartutil.Expand(path, r); err != nil {
+		_ = ret.Close()
+		return nil, fmt.Errorf("error expanding chart: %w", err)
+	}
+
+	// the archive is expanded to a folder with the chart name.
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		_ = ret.Close()
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ret.chartPath = filepath.Join(path, entry.Name())
+		}
+	}
+	if ret.chartPath == "" {
+		_ = ret.Close()
+		return nil, fmt.Errorf("chart folder not found in archive")
+	}
+	return ret, nil
+}
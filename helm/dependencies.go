@@ -0,0 +1,366 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// DependencyFiles are the archives of the dependencies built for a chart.
+type DependencyFiles struct {
+	path       string
+	isTempPath bool
+
+	// LockOutOfDate is set when "Chart.lock" doesn't match the dependencies of "Chart.yaml", so the versions were
+	// resolved from "Chart.yaml".
+	LockOutOfDate bool
+}
+
+// Archives returns the dependency chart archives.
+func (d *DependencyFiles) Archives() ([]string, error) {
+	return filepath.Glob(filepath.Join(d.path, "*.tgz"))
+}
+
+func (d *DependencyFiles) Close() error {
+	if d.isTempPath && d.path != "" {
+		return os.RemoveAll(d.path)
+	}
+	return nil
+}
+
+// BuildDependencies downloads the dependencies of the chart that are from a repository and are missing from its
+// "charts" folder, using the versions of "Chart.lock" if it is up to date, like "helm dependency build". Dependencies
+// from "file://" repositories are not built. It returns nil if no dependency is missing.
+//
+// The repositories added with "helm repo add" are used for the credentials, and the credentials and TLS options are
+// only used for the repository set with WithRepositoryOptionsURL, so they are not sent to other hosts. If the cache is set, the index files are cached, and the dependencies are cached when
+// "Chart.lock" is up to date.
+func BuildDependencies(chartPath string, options ...RepositoryOption) (*DependencyFiles, error) {
+	var optns repositoryOptions
+	for _, opt := range options {
+		opt(&optns)
+	}
+
+	cht, err := loader.LoadDir(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading chart from folder: %w", err)
+	}
+	missing := missingDependencies(cht)
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	settings := cli.New()
+	repoFile, err := repo.LoadFile(settings.RepositoryConfig)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error loading repository config: %w", err)
+	}
+
+	ret := &DependencyFiles{}
+	if cht.Lock != nil {
+		ret.LockOutOfDate = isLockOutOfDate(cht, repoFile)
+	}
+	isLocked := cht.Lock != nil && !ret.LockOutOfDate
+
+	// the build chart has only the missing dependencies, with the repository aliases resolved and the locked versions.
+	var deps []*chart.Dependency
+	for _, dep := range missing {
+		buildDep := *dep
+		buildDep.Repository = resolveRepositoryAlias(dep.Repository, repoFile)
+		if isLocked {
+			for _, locked := range cht.Lock.Dependencies {
+				if locked.Name == dep.Name {
+					buildDep.Version = locked.Version
+				}
+			}
+		}
+		deps = append(deps, &buildDep)
+	}
+
+	var cachePath string
+	if optns.cache != nil && isLocked {
+		depsJSON, err := json.Marshal(deps)
+		if err != nil {
+			return nil, err
+		}
+		key := cacheKey(cht.Metadata.Name, string(depsJSON))
+		cachePath = filepath.Join(optns.cache.dir, cacheDependencyFolder, key)
+		if _, err := os.Stat(cachePath); err == nil {
+			ret.path = cachePath
+			return ret, nil
+		}
+	}
+	if optns.cache != nil && optns.cache.offline {
+		return nil, fmt.Errorf("dependencies of chart %s are not cached", cht.Metadata.Name)
+	}
+
+	// when caching, the build folder is in the cache folder, so the archives can be moved to it.
+	var buildParent string
+	if cachePath != "" {
+		buildParent = filepath.Dir(cachePath)
+		if err := os.MkdirAll(buildParent, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	buildPath, err := os.MkdirTemp(buildParent, "helm-dependencies")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary directory for dependencies: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(buildPath)
+	}()
+
+	buildChartPath := filepath.Join(buildPath, "chart")
+	if err := os.MkdirAll(buildChartPath, 0o755); err != nil {
+		return nil, err
+	}
+	metadata := *cht.Metadata
+	metadata.Dependencies = deps
+	if err := chartutil.SaveChartfile(filepath.Join(buildChartPath, chartutil.ChartfileName), &metadata); err != nil {
+		return nil, err
+	}
+
+	manager := downloader.Manager{
+		Out:              io.Discard,
+		ChartPath:        buildChartPath,
+		Getters:          allGetters,
+		RepositoryConfig: filepath.Join(buildPath, "repositories.yaml"),
+		RepositoryCache:  filepath.Join(buildPath, "cache"),
+	}
+	if optns.cache != nil {
+		manager.RepositoryCache = filepath.Join(optns.cache.dir, cacheIndexFolder)
+	}
+
+	optionsURL := optns.optionsURL
+	if optionsURL != "" && !strings.Contains(optionsURL, "://") && repoFile != nil {
+		if entry := repoFile.Get(optionsURL); entry != nil {
+			optionsURL = entry.URL
+		}
+	}
+
+	buildRepoFile := repo.NewFile()
+	manager.SkipUpdate = true
+	for _, dep := range deps {
+		if registry.IsOCI(dep.Repository) {
+			if manager.RegistryClient == nil {
+				registryOptions := dependencyRegistryOptions(deps, optionsURL, optns)
+				manager.RegistryClient, err = newRegistryClient(&registryOptions)
+				if err != nil {
+					return nil, fmt.Errorf("error creating registry client: %w", err)
+				}
+			}
+			continue
+		}
+		if buildRepoFile.Has(cacheKey(dep.Repository)) {
+			continue
+		}
+		var entryOptions repo.Entry
+		if isSameRepositoryURL(dep.Repository, optionsURL) {
+			entryOptions = optns.entry
+		}
+		entry := dependencyRepositoryEntry(dep.Repository, repoFile, entryOptions)
+		buildRepoFile.Add(entry)
+
+		// the index files are only downloaded if missing or expired.
+		st, err := os.Stat(filepath.Join(manager.RepositoryCache, helmpath.CacheIndexFile(entry.Name)))
+		if optns.cache == nil || err != nil || time.Since(st.ModTime()) >= optns.cache.indexTTL {
+			manager.SkipUpdate = false
+		}
+	}
+	if err := buildRepoFile.WriteFile(manager.RepositoryConfig, 0o600); err != nil {
+		return nil, err
+	}
+
+	if err := manager.Update(); err != nil {
+		return nil, fmt.Errorf("error building dependencies: %w", err)
+	}
+
+	if optns.cache != nil {
+		for _, entry := range buildRepoFile.Repositories {
+			err := optns.cache.writeEntry(manager.RepositoryCache, entry.Name, CacheEntry{
+				Kind:       CacheKindIndex,
+				Repository: entry.URL,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if cachePath != "" {
+		if err := os.Mkdir(cachePath, 0o755); err != nil {
+			return nil, err
+		}
+		if err := moveArchives(filepath.Join(buildChartPath, "charts"), cachePath); err != nil {
+			_ = os.RemoveAll(cachePath)
+			return nil, err
+		}
+		err = optns.cache.writeEntry(filepath.Dir(cachePath), filepath.Base(cachePath), CacheEntry{
+			Kind:    CacheKindDependencies,
+			Chart:   cht.Metadata.Name,
+			Version: cht.Metadata.Version,
+		})
+		if err != nil {
+			return nil, err
+		}
+		ret.path = cachePath
+		return ret, nil
+	}
+
+	ret.path, err = os.MkdirTemp("", "helm-dependencies")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary directory for dependencies: %w", err)
+	}
+	ret.isTempPath = true
+	if err := moveArchives(filepath.Join(buildChartPath, "charts"), ret.path); err != nil {
+		_ = ret.Close()
+		return nil, err
+	}
+	return ret, nil
+}
+
+func moveArchives(src, dest string) error {
+	archives, err := filepath.Glob(filepath.Join(src, "*.tgz"))
+	if err != nil {
+		return err
+	}
+	for _, archive := range archives {
+		if err := os.Rename(archive, filepath.Join(dest, filepath.Base(archive))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingDependencies returns the dependencies from a repository that are not in the "charts" folder.
+func missingDependencies(cht *chart.Chart) []*chart.Dependency {
+	var ret []*chart.Dependency
+	for _, dep := range cht.Metadata.Dependencies {
+		if dep.Repository == "" || strings.HasPrefix(dep.Repository, "file://") {
+			continue
+		}
+		found := false
+		for _, subchart := range cht.Dependencies() {
+			if subchart.Name() == dep.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ret = append(ret, dep)
+		}
+	}
+	return ret
+}
+
+// isLockOutOfDate returns whether "Chart.lock" doesn't match the dependencies of "Chart.yaml", using the same digest
+// as "helm dependency build".
+func isLockOutOfDate(cht *chart.Chart, repoFile *repo.File) bool {
+	var deps []*chart.Dependency
+	for _, dep := range cht.Metadata.Dependencies {
+		resolvedDep := *dep
+		resolvedDep.Repository = resolveRepositoryAlias(dep.Repository, repoFile)
+		deps = append(deps, &resolvedDep)
+	}
+	data, err := json.Marshal([2][]*chart.Dependency{deps, cht.Lock.Dependencies})
+	if err != nil {
+		return true
+	}
+	digest, err := provenance.Digest(bytes.NewBuffer(data))
+	return err != nil || "sha256:"+digest != cht.Lock.Digest
+}
+
+// resolveRepositoryAlias returns the URL of "@name" or "alias:name" repositories added with "helm repo add".
+func resolveRepositoryAlias(repository string, repoFile *repo.File) string {
+	name, ok := strings.CutPrefix(repository, "@")
+	if !ok {
+		name, ok = strings.CutPrefix(repository, "alias:")
+	}
+	if !ok || repoFile == nil {
+		return repository
+	}
+	if entry := repoFile.Get(name); entry != nil {
+		return entry.URL
+	}
+	return repository
+}
+
+// dependencyRepositoryEntry returns the repository entry for the URL, with the settings of the repository added
+// with "helm repo add" if any, and the set options, which must only be the ones set for this repository. The name is
+// the cache key of the URL.
+func dependencyRepositoryEntry(repoURL string, repoFile *repo.File, options repo.Entry) *repo.Entry {
+	entry := repo.Entry{
+		URL: repoURL,
+	}
+	if repoFile != nil {
+		for _, userEntry := range repoFile.Repositories {
+			if isSameRepositoryURL(userEntry.URL, repoURL) {
+				entry = *userEntry
+				break
+			}
+		}
+	}
+	entry.Name = cacheKey(repoURL)
+	if options.Username != "" || options.Password != "" {
+		entry.Username, entry.Password = options.Username, options.Password
+	}
+	if options.PassCredentialsAll {
+		entry.PassCredentialsAll = true
+	}
+	if options.CertFile != "" || options.KeyFile != "" || options.CAFile != "" {
+		entry.CertFile, entry.KeyFile, entry.CAFile = options.CertFile, options.KeyFile, options.CAFile
+	}
+	if options.InsecureSkipTLSverify {
+		entry.InsecureSkipTLSverify = true
+	}
+	return &entry
+}
+
+// dependencyRegistryOptions returns the options of the registry client of the OCI dependencies. As the client is
+// shared, the credentials and TLS options are only used if all the OCI dependencies are from the registry of the
+// repository they were set for, and otherwise only the registry config file is used.
+func dependencyRegistryOptions(deps []*chart.Dependency, optionsURL string, options repositoryOptions) repositoryOptions {
+	optionsHost, ok := ociRegistryHost(optionsURL)
+	for _, dep := range deps {
+		if !registry.IsOCI(dep.Repository) {
+			continue
+		}
+		if host, _ := ociRegistryHost(dep.Repository); !ok || host != optionsHost {
+			options.entry = repo.Entry{}
+			options.registryUsername, options.registryPassword = "", ""
+			break
+		}
+	}
+	return options
+}
+
+// ociRegistryHost returns the registry host of an "oci://" URL.
+func ociRegistryHost(repoURL string) (string, bool) {
+	if !registry.IsOCI(repoURL) {
+		return "", false
+	}
+	host, _, _ := strings.Cut(strings.TrimPrefix(repoURL, registry.OCIScheme+"://"), "/")
+	return host, host != ""
+}
+
+// isSameRepositoryURL returns whether the repository URLs are the same, ignoring a trailing slash.
+func isSameRepositoryURL(a, b string) bool {
+	return a != "" && strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
@@ -0,0 +1,137 @@
+package helm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+func TestBuildDependenciesCredentials(t *testing.T) {
+	t.Setenv("HELM_REPOSITORY_CONFIG", filepath.Join(t.TempDir(), "repositories.yaml"))
+
+	var mu sync.Mutex
+	authorizations := map[string][]string{}
+	newRepository := func(name string) *httptest.Server {
+		dir := t.TempDir()
+		archive, err := chartutil.Save(&chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: name, Version: "1.0.0"},
+		}, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			authorizations[name] = append(authorizations[name], r.Header.Get("Authorization"))
+			mu.Unlock()
+			http.FileServer(http.Dir(dir)).ServeHTTP(w, r)
+		}))
+		t.Cleanup(server.Close)
+
+		digest, err := provenance.DigestFile(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index := repo.NewIndexFile()
+		if err := index.MustAdd(&chart.Metadata{APIVersion: chart.APIVersionV2, Name: name, Version: "1.0.0"},
+			filepath.Base(archive), server.URL, digest); err != nil {
+			t.Fatal(err)
+		}
+		if err := index.WriteFile(filepath.Join(dir, "index.yaml"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return server
+	}
+	own := newRepository("own")
+	other := newRepository("other")
+
+	chartPath := filepath.Join(t.TempDir(), "app")
+	chartFile, err := yaml.Marshal(&chart.Metadata{
+		APIVersion: chart.APIVersionV2,
+		Name:       "app",
+		Version:    "1.0.0",
+		Dependencies: []*chart.Dependency{
+			{Name: "own", Version: "1.0.0", Repository: own.URL},
+			{Name: "other", Version: "1.0.0", Repository: other.URL},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(chartPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(chartPath, chartutil.ChartfileName), chartFile, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := BuildDependencies(chartPath,
+		WithRepositoryBasicAuth("user", "secret"),
+		WithRepositoryOptionsURL(own.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer files.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(authorizations["own"]) == 0 || len(authorizations["other"]) == 0 {
+		t.Fatalf("expected requests to both repositories, got %v", authorizations)
+	}
+	for _, authorization := range authorizations["own"] {
+		if authorization == "" {
+			t.Fatal("expected the credentials to be sent to the chart repository")
+		}
+	}
+	for _, authorization := range authorizations["other"] {
+		if authorization != "" {
+			t.Fatalf("expected no credentials sent to another repository, got %q", authorization)
+		}
+	}
+}
+
+func TestDependencyRegistryOptions(t *testing.T) {
+	options := repositoryOptions{
+		entry:            repo.Entry{CAFile: "ca.pem"},
+		registryUsername: "user",
+		registryPassword: "secret",
+	}
+	deps := func(repositories ...string) []*chart.Dependency {
+		var ret []*chart.Dependency
+		for _, repository := range repositories {
+			ret = append(ret, &chart.Dependency{Repository: repository})
+		}
+		return ret
+	}
+
+	tests := []struct {
+		name       string
+		deps       []*chart.Dependency
+		optionsURL string
+		want       bool
+	}{
+		{name: "same registry", deps: deps("oci://ghcr.io/myorg/a", "oci://ghcr.io/other/b"),
+			optionsURL: "oci://ghcr.io/myorg/charts", want: true},
+		{name: "other registry", deps: deps("oci://ghcr.io/myorg/a", "oci://registry.example.com/b"),
+			optionsURL: "oci://ghcr.io/myorg/charts", want: false},
+		{name: "HTTP repository", deps: deps("oci://ghcr.io/myorg/a"),
+			optionsURL: "https://charts.example.com", want: false},
+		{name: "no repository", deps: deps("oci://ghcr.io/myorg/a"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dependencyRegistryOptions(tt.deps, tt.optionsURL, options)
+			if hasOptions := got.registryUsername != "" && got.entry.CAFile != ""; hasOptions != tt.want {
+				t.Fatalf("expected options %v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
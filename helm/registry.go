@@ -0,0 +1,90 @@
+package helm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func loadRepositoryOCI(options *repositoryOptions) (*Repository, error) {
+	registryClient, err := newRegistryClient(options)
+	if err != nil {
+		return nil, fmt.Errorf("error creating registry client: %w", err)
+	}
+
+	return &Repository{
+		repository: &repo.ChartRepository{
+			Config: &options.entry,
+		},
+		index:    nil,
+		registry: registryClient,
+		cache:    options.cache,
+	}, nil
+}
+
+// newRegistryClient creates an OCI registry client. Credentials are read from the registry config file, or from the
+// Docker config file, unless a username is set. Nothing is written to the credentials files.
+func newRegistryClient(options *repositoryOptions) (*registry.Client, error) {
+	clientOptions := []registry.ClientOption{
+		registry.ClientOptEnableCache(false),
+		registry.ClientOptWriter(io.Discard),
+	}
+	if options.registryConfig != "" {
+		clientOptions = append(clientOptions, registry.ClientOptCredentialsFile(options.registryConfig))
+	}
+	if options.registryUsername != "" {
+		clientOptions = append(clientOptions,
+			registry.ClientOptBasicAuth(options.registryUsername, options.registryPassword))
+	}
+	if options.plainHTTP {
+		clientOptions = append(clientOptions, registry.ClientOptPlainHTTP())
+	}
+
+	entry := options.entry
+	if entry.CertFile != "" || entry.KeyFile != "" || entry.CAFile != "" || entry.InsecureSkipTLSverify {
+		tlsConfig, err := newTLSConfig(entry.CertFile, entry.KeyFile, entry.CAFile, entry.InsecureSkipTLSverify)
+		if err != nil {
+			return nil, fmt.Errorf("can't create TLS config for client: %w", err)
+		}
+		clientOptions = append(clientOptions, registry.ClientOptHTTPClient(&http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+				Proxy:           http.ProxyFromEnvironment,
+			},
+		}))
+	}
+
+	return registry.NewClient(clientOptions...)
+}
+
+// newTLSConfig returns the TLS client config, like Helm's internal one.
+func newTLSConfig(certFile, keyFile, caFile string, insecureSkipTLSVerify bool) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: insecureSkipTLSVerify,
+	}
+	if certFile != "" && keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load key pair from cert %s and key %s: %w", certFile, keyFile, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		caCerts, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read CA file %s: %w", caFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCerts) {
+			return nil, fmt.Errorf("failed to append certificates from file: %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
@@ -0,0 +1,263 @@
+package helm
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+func TestRegistryChart(t *testing.T) {
+	server := newTestRegistry(t, "user", "secret", map[string][]string{"web": {"1.0.0", "1.1.0"}})
+	repoURL := "oci://" + strings.TrimPrefix(server.URL, "http://") + "/charts"
+	options := []RepositoryOption{
+		WithRegistryConfig(filepath.Join(t.TempDir(), "config.json")),
+		WithRegistryPlainHTTP(true),
+		WithRegistryBasicAuth("user", "secret"),
+	}
+
+	repository, err := LoadRepository(repoURL, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repository.Close()
+
+	var versions []string
+	for cv, err := range repository.ChartVersions("web", 0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, cv.Version)
+	}
+	if want := []string{"1.1.0", "1.0.0"}; !reflect.DeepEqual(versions, want) {
+		t.Fatalf("expected versions %v, got %v", want, versions)
+	}
+
+	cht, err := repository.GetChart("web", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := cht.Download()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer files.Close()
+	chartFile, err := chartutil.LoadChartfile(filepath.Join(files.ChartPath(), chartutil.ChartfileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chartFile.Name != "web" || chartFile.Version != "1.0.0" {
+		t.Fatalf("expected chart web 1.0.0, got %s %s", chartFile.Name, chartFile.Version)
+	}
+}
+
+func TestRegistryChartCache(t *testing.T) {
+	server := newTestRegistry(t, "user", "secret", map[string][]string{"web": {"1.0.0"}})
+	repoURL := "oci://" + strings.TrimPrefix(server.URL, "http://") + "/charts"
+	cacheDir := t.TempDir()
+	options := []RepositoryOption{
+		WithRegistryConfig(filepath.Join(t.TempDir(), "config.json")),
+		WithRegistryPlainHTTP(true),
+		WithRegistryBasicAuth("user", "secret"),
+	}
+
+	download := func(cache *Cache) error {
+		repository, err := LoadRepository(repoURL, append(options, WithRepositoryCache(cache))...)
+		if err != nil {
+			return err
+		}
+		defer repository.Close()
+		cht, err := repository.GetChart("web", "1.0.0")
+		if err != nil {
+			return err
+		}
+		files, err := cht.Download()
+		if err != nil {
+			return err
+		}
+		return files.Close()
+	}
+
+	if err := download(NewCache(cacheDir)); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	// the registry is not used anymore.
+	if err := download(NewCache(cacheDir, WithCacheOffline(true))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegistryChartDownloadPath(t *testing.T) {
+	server := newTestRegistry(t, "user", "secret", map[string][]string{"web": {"1.0.0"}})
+	repoURL := "oci://" + strings.TrimPrefix(server.URL, "http://") + "/charts"
+
+	repository, err := LoadRepository(repoURL,
+		WithRegistryConfig(filepath.Join(t.TempDir(), "config.json")),
+		WithRegistryPlainHTTP(true),
+		WithRegistryBasicAuth("user", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repository.Close()
+	cht, err := repository.GetChart("web", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downloadPath := t.TempDir()
+	files, err := cht.Download(WithChartDownloadPath(downloadPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(downloadPath, "web"); files.ChartPath() != want {
+		t.Fatalf("expected chart path %s, got %s", want, files.ChartPath())
+	}
+	if err := files.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the download path is not removed.
+	if _, err := os.Stat(filepath.Join(downloadPath, "web", chartutil.ChartfileName)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegistryAuthentication(t *testing.T) {
+	server := newTestRegistry(t, "user", "secret", map[string][]string{"web": {"1.0.0"}})
+	repoURL := "oci://" + strings.TrimPrefix(server.URL, "http://") + "/charts"
+
+	repository, err := LoadRepository(repoURL,
+		WithRegistryConfig(filepath.Join(t.TempDir(), "config.json")),
+		WithRegistryPlainHTTP(true),
+		WithRegistryBasicAuth("user", "wrong"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repository.Close()
+	for _, err := range repository.ChartVersions("web", 0) {
+		if err == nil {
+			t.Fatal("expected an authentication error")
+		}
+		break
+	}
+}
+
+// newTestRegistry starts an OCI registry serving the versions of the charts, in the "charts" namespace, which
+// requires basic authentication.
+func newTestRegistry(t *testing.T, username, password string, charts map[string][]string) *httptest.Server {
+	t.Helper()
+
+	type blob struct {
+		mediaType string
+		data      []byte
+	}
+	digest := func(data []byte) string {
+		return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	}
+
+	blobs := map[string]blob{}
+	manifests := map[string][]byte{}
+	tags := map[string][]string{}
+	for name, versions := range charts {
+		for _, version := range versions {
+			archive, err := chartutil.Save(&chart.Chart{
+				Metadata: &chart.Metadata{
+					APIVersion: chart.APIVersionV2,
+					Name:       name,
+					Version:    version,
+				},
+				Templates: []*chart.File{{
+					Name: "templates/configmap.yaml",
+					Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\n"),
+				}},
+			}, t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err := json.Marshal(map[string]string{"apiVersion": chart.APIVersionV2, "name": name,
+				"version": version})
+			if err != nil {
+				t.Fatal(err)
+			}
+			blobs[digest(config)] = blob{registry.ConfigMediaType, config}
+			blobs[digest(data)] = blob{registry.ChartLayerMediaType, data}
+			manifest, err := json.Marshal(map[string]any{
+				"schemaVersion": 2,
+				"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+				"config": map[string]any{
+					"mediaType": registry.ConfigMediaType,
+					"digest":    digest(config),
+					"size":      len(config),
+				},
+				"layers": []any{map[string]any{
+					"mediaType": registry.ChartLayerMediaType,
+					"digest":    digest(data),
+					"size":      len(data),
+				}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			manifests[name+"/"+version] = manifest
+			manifests[name+"/"+digest(manifest)] = manifest
+			tags[name] = append(tags[name], version)
+		}
+	}
+
+	serve := func(w http.ResponseWriter, r *http.Request, mediaType string, data []byte) {
+		w.Header().Set("Content-Type", mediaType)
+		w.Header().Set("Docker-Content-Digest", digest(data))
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if r.Method != http.MethodHead {
+			_, _ = w.Write(data)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != username || p != password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/v2/charts/")
+		name, resource, _ := strings.Cut(path, "/")
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case resource == "tags/list":
+			_ = json.NewEncoder(w).Encode(map[string]any{"name": "charts/" + name, "tags": tags[name]})
+		case strings.HasPrefix(resource, "manifests/"):
+			manifest, ok := manifests[name+"/"+strings.TrimPrefix(resource, "manifests/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			serve(w, r, "application/vnd.oci.image.manifest.v1+json", manifest)
+		case strings.HasPrefix(resource, "blobs/"):
+			b, ok := blobs[strings.TrimPrefix(resource, "blobs/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			serve(w, r, b.mediaType, b.data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
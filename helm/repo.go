@@ -8,6 +8,8 @@ import (
 	"strings"
 
 	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/helmpath"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/repo"
@@ -17,28 +19,97 @@ type Repository struct {
 	repository *repo.ChartRepository
 	index      *repo.IndexFile
 	registry   *registry.Client
+	cache      *Cache
+	// userCache is set when the index file is in the Helm repository cache or in the cache, which must not be
+	// removed.
+	userCache bool
 }
 
-func LoadRepository(repoURL string) (*Repository, error) {
-	c := repo.Entry{
-		URL:                   repoURL,
-		Username:              "",
-		Password:              "",
-		PassCredentialsAll:    false,
-		CertFile:              "",
-		KeyFile:               "",
-		CAFile:                "",
-		Name:                  randomName(),
-		InsecureSkipTLSverify: false,
+func LoadRepository(repoURL string, options ...RepositoryOption) (*Repository, error) {
+	optns := repositoryOptions{
+		entry: repo.Entry{
+			URL:  repoURL,
+			Name: randomName(),
+		},
+	}
+	for _, opt := range options {
+		opt(&optns)
 	}
 	if registry.IsOCI(repoURL) {
-		return loadRepositoryOCI(&c)
+		return loadRepositoryOCI(&optns)
 	}
-	repository, err := repo.NewChartRepository(&c, allGetters)
+	repository, err := repo.NewChartRepository(&optns.entry, allGetters)
 	if err != nil {
 		return nil, fmt.Errorf("error loading repository %s: %w", repoURL, err)
 	}
-	return loadRepository(repository)
+	if optns.cache == nil {
+		return loadRepository(repository)
+	}
+
+	indexFilename, err := optns.cache.repositoryIndex(repository)
+	if err != nil {
+		return nil, fmt.Errorf("error loading repository index file: %w", err)
+	}
+	ret, err := loadRepositoryIndex(repository, indexFilename)
+	if err != nil {
+		return nil, err
+	}
+	ret.cache = optns.cache
+	ret.userCache = true
+	return ret, nil
+}
+
+// HasRepositoryEntry returns whether a repository was added with "helm repo add" with the name, in Helm's repository
+// config.
+func HasRepositoryEntry(name string) bool {
+	repoFile, err := repo.LoadFile(cli.New().RepositoryConfig)
+	return err == nil && repoFile.Has(name)
+}
+
+// LoadRepositoryEntry loads a repository added with "helm repo add", using Helm's repository config and the cached
+// index file, which is downloaded to the cache if missing. The options are applied over the repository settings.
+func LoadRepositoryEntry(name string, options ...RepositoryOption) (*Repository, error) {
+	settings := cli.New()
+
+	repoFile, err := repo.LoadFile(settings.RepositoryConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error loading repository config: %w", err)
+	}
+	entry := repoFile.Get(name)
+	if entry == nil {
+		return nil, fmt.Errorf("repository %s not found in %s", name, settings.RepositoryConfig)
+	}
+
+	optns := repositoryOptions{
+		entry: *entry,
+	}
+	for _, opt := range options {
+		opt(&optns)
+	}
+	repository, err := repo.NewChartRepository(&optns.entry, allGetters)
+	if err != nil {
+		return nil, fmt.Errorf("error loading repository %s: %w", name, err)
+	}
+	repository.CachePath = settings.RepositoryCache
+
+	indexFilename := filepath.Join(settings.RepositoryCache, helmpath.CacheIndexFile(name))
+	if _, err := os.Stat(indexFilename); err != nil {
+		if optns.cache != nil && optns.cache.Offline() {
+			return nil, fmt.Errorf("index of repository %s is not cached", name)
+		}
+		indexFilename, err = repository.DownloadIndexFile()
+		if err != nil {
+			return nil, fmt.Errorf("error downloading repository index file: %w", err)
+		}
+	}
+
+	ret, err := loadRepositoryIndex(repository, indexFilename)
+	if err != nil {
+		return nil, err
+	}
+	ret.cache = optns.cache
+	ret.userCache = true
+	return ret, nil
 }
 
 func loadRepository(repository *repo.ChartRepository) (*Repository, error) {
@@ -46,7 +117,10 @@ func loadRepository(repository *repo.ChartRepository) (*Repository, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error downloading repository index file: %w", err)
 	}
+	return loadRepositoryIndex(repository, indexFilename)
+}
 
+func loadRepositoryIndex(repository *repo.ChartRepository, indexFilename string) (*Repository, error) {
 	// Read the index file for the repository to get chart information and return chart URL
 	repoIndex, err := repo.LoadIndexFile(indexFilename)
 	if err != nil {
@@ -59,40 +133,37 @@ func loadRepository(repository *repo.ChartRepository) (*Repository, error) {
 	}, nil
 }
 
-func loadRepositoryOCI(entry *repo.Entry) (*Repository, error) {
-	// ref := strings.TrimPrefix(entry.URL, fmt.Sprintf("%s://", registry.OCIScheme))
+func (r *Repository) ResolveReferenceURL(url string) (string, error) {
+	return repo.ResolveReferenceURL(r.repository.Config.URL, url)
+}
 
-	registryClient, err := registry.NewClient(
-		// registry.ClientOptDebug(true),
-		registry.ClientOptEnableCache(false),
-		// registry.ClientOptWriter(os.Stdout),
-		// registry.ClientOptCredentialsFile(credentialsFile),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("error creating registry client: %w", err)
+// getterOptions returns the authentication and TLS options of the repository to download the URL. The credentials
+// are only sent to other hosts if "pass credentials all" is set, like Helm does.
+func (r *Repository) getterOptions(downloadURL string) []getter.Option {
+	if r.registry != nil {
+		// the registry client was created with the credentials and TLS options.
+		return []getter.Option{
+			getter.WithRegistryClient(r.registry),
+		}
 	}
 
-	// err = registryClient.Login(
-	// 	ref,
-	// 	// registry.LoginOptBasicAuth(srv.TestUsername, srv.TestPassword),
-	// 	// registry.LoginOptInsecure(true),
-	// 	// registry.LoginOptPlainText(true),
-	// )
-	// if err != nil {
-	// 	return nil, fmt.Errorf("error logging into registry with good credentials: %w", err)
-	// }
-
-	return &Repository{
-		repository: &repo.ChartRepository{
-			Config: entry,
-		},
-		index:    nil,
-		registry: registryClient,
-	}, nil
+	return entryGetterOptions(r.repository.Config, downloadURL)
 }
 
-func (r *Repository) ResolveReferenceURL(url string) (string, error) {
-	return repo.ResolveReferenceURL(r.repository.Config.URL, url)
+func entryGetterOptions(entry *repo.Entry, downloadURL string) []getter.Option {
+	options := []getter.Option{
+		getter.WithInsecureSkipVerifyTLS(entry.InsecureSkipTLSverify),
+	}
+	if entry.CertFile != "" || entry.KeyFile != "" || entry.CAFile != "" {
+		options = append(options, getter.WithTLSClientConfig(entry.CertFile, entry.KeyFile, entry.CAFile))
+	}
+	if entry.Username != "" && entry.Password != "" && (entry.PassCredentialsAll || sameHost(entry.URL, downloadURL)) {
+		options = append(options,
+			getter.WithBasicAuth(entry.Username, entry.Password),
+			getter.WithPassCredentialsAll(entry.PassCredentialsAll),
+		)
+	}
+	return options
 }
 
 func (r *Repository) GetChart(name, version string) (*Chart, error) {
@@ -164,6 +235,11 @@ func (r *Repository) chartVersionsOCI(name string, maxAmount int) iter.Seq2[*rep
 		// 	yield(nil, errors.New("cannot list chart from OCI registry"))
 		// }
 
+		if r.cache != nil && r.cache.Offline() {
+			yield(nil, fmt.Errorf("cannot list the tags of chart %s in offline mode", name))
+			return
+		}
+
 		ref := strings.TrimPrefix(JoinHTTPPaths(r.repository.Config.URL, name), fmt.Sprintf("%s://", registry.OCIScheme))
 		tags, err := r.registry.Tags(ref)
 		if err != nil {
@@ -190,10 +266,92 @@ func (r *Repository) chartVersionsOCI(name string, maxAmount int) iter.Seq2[*rep
 }
 
 func (r *Repository) Close() error {
-	if r.repository.CachePath == "" {
+	if r.userCache || r.repository.CachePath == "" {
 		return nil
 	}
 	_ = os.RemoveAll(filepath.Join(r.repository.CachePath, helmpath.CacheChartsFile(r.repository.Config.Name)))
 	_ = os.RemoveAll(filepath.Join(r.repository.CachePath, helmpath.CacheIndexFile(r.repository.Config.Name)))
 	return nil
 }
+
+// WithRepositoryBasicAuth sets the username and password of the repository.
+func WithRepositoryBasicAuth(username, password string) RepositoryOption {
+	return func(options *repositoryOptions) {
+		options.entry.Username = username
+		options.entry.Password = password
+	}
+}
+
+// WithRepositoryPassCredentialsAll sends the credentials to all domains, not only the repository one.
+func WithRepositoryPassCredentialsAll(passCredentialsAll bool) RepositoryOption {
+	return func(options *repositoryOptions) {
+		options.entry.PassCredentialsAll = passCredentialsAll
+	}
+}
+
+// WithRepositoryTLSClientConfig sets the client certificate and key, and the CA bundle used to verify the server.
+func WithRepositoryTLSClientConfig(certFile, keyFile, caFile string) RepositoryOption {
+	return func(options *repositoryOptions) {
+		options.entry.CertFile = certFile
+		options.entry.KeyFile = keyFile
+		options.entry.CAFile = caFile
+	}
+}
+
+// WithRepositoryInsecureSkipTLSVerify skips the verification of the server certificate.
+func WithRepositoryInsecureSkipTLSVerify(insecureSkipTLSVerify bool) RepositoryOption {
+	return func(options *repositoryOptions) {
+		options.entry.InsecureSkipTLSverify = insecureSkipTLSVerify
+	}
+}
+
+// WithRepositoryCache caches the index files and chart archives.
+func WithRepositoryCache(cache *Cache) RepositoryOption {
+	return func(options *repositoryOptions) {
+		options.cache = cache
+	}
+}
+
+// WithRegistryConfig sets the OCI registry credentials file. The default is the Helm one, falling back to the Docker
+// config file.
+func WithRegistryConfig(registryConfig string) RepositoryOption {
+	return func(options *repositoryOptions) {
+		options.registryConfig = registryConfig
+	}
+}
+
+// WithRegistryBasicAuth sets the OCI registry username and password, instead of the ones from the credentials file.
+func WithRegistryBasicAuth(username, password string) RepositoryOption {
+	return func(options *repositoryOptions) {
+		options.registryUsername = username
+		options.registryPassword = password
+	}
+}
+
+// WithRegistryPlainHTTP uses HTTP instead of HTTPS to connect to the OCI registry.
+func WithRegistryPlainHTTP(plainHTTP bool) RepositoryOption {
+	return func(options *repositoryOptions) {
+		options.plainHTTP = plainHTTP
+	}
+}
+
+// WithRepositoryOptionsURL sets the URL, or the "helm repo add" name, of the repository the credentials and TLS
+// options were set for. When building dependencies, they are only used for the dependencies from this repository, or
+// from its registry for OCI repositories.
+func WithRepositoryOptionsURL(repoURL string) RepositoryOption {
+	return func(options *repositoryOptions) {
+		options.optionsURL = repoURL
+	}
+}
+
+type RepositoryOption func(*repositoryOptions)
+
+type repositoryOptions struct {
+	entry            repo.Entry
+	optionsURL       string
+	registryConfig   string
+	registryUsername string
+	registryPassword string
+	plainHTTP        bool
+	cache            *Cache
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
+	"net/url"
 	"strings"
 )
 
@@ -16,3 +17,16 @@ func randomName() string {
 func JoinHTTPPaths(baseURL, paths string) string {
 	return fmt.Sprintf("%s/%s", strings.TrimSuffix(strings.TrimSpace(baseURL), "/"), paths)
 }
+
+// sameHost returns whether both URLs have the same scheme and host.
+func sameHost(url1, url2 string) bool {
+	u1, err := url.Parse(url1)
+	if err != nil {
+		return false
+	}
+	u2, err := url.Parse(url2)
+	if err != nil {
+		return false
+	}
+	return u1.Scheme == u2.Scheme && u1.Host == u2.Host
+}
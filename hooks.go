@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"sort"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// hookEventOrder is the order the hook events are shown, following the release lifecycle.
+var hookEventOrder = []release.HookEvent{
+	release.HookPreInstall,
+	release.HookPostInstall,
+	release.HookPreUpgrade,
+	release.HookPostUpgrade,
+	release.HookPreRollback,
+	release.HookPostRollback,
+	release.HookPreDelete,
+	release.HookPostDelete,
+	release.HookTest,
+}
+
+// sortManifests separates the hooks from the regular manifests using Helm's manifest sorting. The hooks are
+// grouped by event in the order Helm executes them, and the regular manifests are returned in install order.
+// The files map is keyed by the full template path, and templateNames maps it to the template name shown to the
+// user. A manifest that fails to parse returns an error, as Helm fails the install.
+func sortManifests(files map[string]string, templateNames map[string]string,
+	caps *chartutil.Capabilities) ([]apiDataHookEvent, []apiDataObject, error) {
+	apis := chartutil.DefaultVersionSet
+	if caps != nil {
+		apis = caps.APIVersions
+	}
+
+	hooks, manifests, err := releaseutil.SortManifests(files, apis, releaseutil.InstallOrder)
+	if err != nil {
+		// the Helm error has a stack trace, which slog would print.
+		return nil, nil, errors.New(err.Error())
+	}
+
+	var installOrder []apiDataObject
+	for _, manifest := range manifests {
+		if obj, ok := parseObject(templateNames[manifest.Name], manifest.Content); ok {
+			obj.Source = manifest.Name
+			installOrder = append(installOrder, obj)
+		}
+	}
+
+	// Helm executes the hooks of each event sorted by weight, and then by name.
+	sort.SliceStable(hooks, func(i, j int) bool {
+		if hooks[i].Weight == hooks[j].Weight {
+			return hooks[i].Name < hooks[j].Name
+		}
+		return hooks[i].Weight < hooks[j].Weight
+	})
+
+	var hookEvents []apiDataHookEvent
+	for _, event := range hookEventOrder {
+		hookEvent := apiDataHookEvent{
+			Event: event.String(),
+		}
+		for _, hook := range hooks {
+			if !hasHookEvent(hook, event) {
+				continue
+			}
+			apiHook := apiDataHook{
+				Kind:     hook.Kind,
+				Name:     hook.Name,
+				Template: templateNames[hook.Path],
+				Weight:   hook.Weight,
+				Content:  ensureNewline(hook.Manifest),
+			}
+			for _, policy := range hook.DeletePolicies {
+				apiHook.DeletePolicies = append(apiHook.DeletePolicies, policy.String())
+			}
+			if len(apiHook.DeletePolicies) == 0 {
+				// Helm uses "before-hook-creation" when no policy is set.
+				apiHook.DeletePolicies = []string{release.HookBeforeHookCreation.String()}
+				apiHook.DefaultDeletePolicy = true
+			}
+			hookEvent.Hooks = append(hookEvent.Hooks, apiHook)
+		}
+		if len(hookEvent.Hooks) > 0 {
+			hookEvents = append(hookEvents, hookEvent)
+		}
+	}
+
+	return hookEvents, installOrder, nil
+}
+
+func hasHookEvent(hook *release.Hook, event release.HookEvent) bool {
+	for _, hookEvent := range hook.Events {
+		if hookEvent == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortManifests(t *testing.T) {
+	hook := func(kind, name, events, weight, policy string) string {
+		manifest := "apiVersion: v1\nkind: " + kind + "\nmetadata:\n  name: " + name + "\n  annotations:\n" +
+			"    helm.sh/hook: " + events + "\n"
+		if weight != "" {
+			manifest += "    helm.sh/hook-weight: \"" + weight + "\"\n"
+		}
+		if policy != "" {
+			manifest += "    helm.sh/hook-delete-policy: " + policy + "\n"
+		}
+		return manifest
+	}
+	files := map[string]string{
+		"c/templates/app.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n---\n" +
+			"apiVersion: v1\nkind: Service\nmetadata:\n  name: app\n---\n" +
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n",
+		"c/templates/ns.yaml": "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: app\n",
+		"c/templates/hooks.yaml": hook("Job", "migrate-b", "pre-install,pre-upgrade", "5", "hook-succeeded") +
+			"---\n" + hook("Job", "migrate-a", "pre-install", "5", "") +
+			"---\n" + hook("Job", "setup", "pre-install", "-1", "") +
+			"---\n" + hook("Pod", "test", "test", "", "before-hook-creation,hook-succeeded"),
+	}
+	templateNames := map[string]string{
+		"c/templates/app.yaml":   "templates/app.yaml",
+		"c/templates/ns.yaml":    "templates/ns.yaml",
+		"c/templates/hooks.yaml": "templates/hooks.yaml",
+	}
+
+	hookEvents, manifests, err := sortManifests(files, templateNames, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type hookResult struct {
+		Name                string
+		Weight              int
+		DeletePolicies      []string
+		DefaultDeletePolicy bool
+	}
+	gotHooks := map[string][]hookResult{}
+	var gotEvents []string
+	for _, event := range hookEvents {
+		gotEvents = append(gotEvents, event.Event)
+		for _, h := range event.Hooks {
+			if h.Template != "templates/hooks.yaml" {
+				t.Fatalf("unexpected template %q of hook %s", h.Template, h.Name)
+			}
+			gotHooks[event.Event] = append(gotHooks[event.Event], hookResult{
+				Name:                h.Name,
+				Weight:              h.Weight,
+				DeletePolicies:      h.DeletePolicies,
+				DefaultDeletePolicy: h.DefaultDeletePolicy,
+			})
+		}
+	}
+	// events in lifecycle order, hooks by weight and then by name.
+	if want := []string{"pre-install", "pre-upgrade", "test"}; !reflect.DeepEqual(gotEvents, want) {
+		t.Fatalf("expected events %v, got %v", want, gotEvents)
+	}
+	wantHooks := map[string][]hookResult{
+		"pre-install": {
+			{Name: "setup", Weight: -1, DeletePolicies: []string{"before-hook-creation"}, DefaultDeletePolicy: true},
+			{Name: "migrate-a", Weight: 5, DeletePolicies: []string{"before-hook-creation"}, DefaultDeletePolicy: true},
+			{Name: "migrate-b", Weight: 5, DeletePolicies: []string{"hook-succeeded"}},
+		},
+		"pre-upgrade": {
+			{Name: "migrate-b", Weight: 5, DeletePolicies: []string{"hook-succeeded"}},
+		},
+		"test": {
+			{Name: "test", DeletePolicies: []string{"before-hook-creation", "hook-succeeded"}},
+		},
+	}
+	if !reflect.DeepEqual(gotHooks, wantHooks) {
+		t.Fatalf("expected hooks %+v, got %+v", wantHooks, gotHooks)
+	}
+
+	var gotManifests []string
+	for _, obj := range manifests {
+		gotManifests = append(gotManifests, obj.Kind+"/"+obj.Name+" "+obj.Template+" "+obj.Source)
+	}
+	// install order.
+	wantManifests := []string{
+		"Namespace/app templates/ns.yaml c/templates/ns.yaml",
+		"ConfigMap/app templates/app.yaml c/templates/app.yaml",
+		"Service/app templates/app.yaml c/templates/app.yaml",
+		"Deployment/app templates/app.yaml c/templates/app.yaml",
+	}
+	if !reflect.DeepEqual(gotManifests, wantManifests) {
+		t.Fatalf("expected manifests %v, got %v", wantManifests, gotManifests)
+	}
+}
+
+func TestSortManifestsError(t *testing.T) {
+	files := map[string]string{
+		"c/templates/bad.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata: [\n",
+	}
+	templateNames := map[string]string{"c/templates/bad.yaml": "templates/bad.yaml"}
+	if _, _, err := sortManifests(files, templateNames, nil); err == nil {
+		t.Fatal("expected an error for an invalid manifest")
+	}
+}
@@ -8,118 +8,173 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
-	"path"
 	"strings"
+	"sync"
 
-	"helm.sh/helm/v3/pkg/chart"
-	"helm.sh/helm/v3/pkg/chartutil"
-	"helm.sh/helm/v3/pkg/engine"
 	"sigs.k8s.io/yaml"
 )
 
 const devHTTPPort = 17821
 
-func runHTTP(ctx context.Context, httpPort int, chart *chart.Chart, valueFiles []string, values map[string]any,
-	releaseOptions chartutil.ReleaseOptions, chartVersions []string) error {
-	fnprefix := fmt.Sprintf("%s/templates/", chart.Name())
+// devUIOrigin is the origin of the UI development server ("npm start"), the only one allowed to call the API from
+// another origin.
+const devUIOrigin = "http://localhost:3000"
 
-	valuesToRender, err := chartutil.ToRenderValues(chart, values, releaseOptions, nil)
-	if err != nil {
-		return err
-	}
+// dataUpdatedEvent is sent to the browser when the rendered data changes.
+const dataUpdatedEvent = "updated"
 
-	renderedTemplate, err := engine.Render(chart, valuesToRender)
-	if err != nil {
-		return fmt.Errorf("cannot render template using engine: %v", err)
+// editedValuesSource is the value source of the values edited in the browser.
+const editedValuesSource = "values edited in the browser"
+
+type httpServer struct {
+	watch  bool
+	events *eventBroker
+	loader optionsLoader
+
+	mu           sync.RWMutex
+	options      renderOptions
+	chartSource  *repositoryChart
+	closeOptions func()
+	stopWatch    context.CancelFunc
+	data         apiData
+}
+
+func runHTTP(ctx context.Context, httpPort int, loader optionsLoader, env string, watch bool) error {
+	server := &httpServer{
+		watch:  watch,
+		events: newEventBroker(),
+		loader: loader,
 	}
+	defer server.close()
 
-	chartStr, err := yaml.Marshal(chart.Metadata)
-	if err != nil {
+	if err := server.loadEnvironment(ctx, env); err != nil {
 		return err
 	}
 
-	chartStrValue := string(chartStr)
+	// render errors are shown in the browser, so the server is always started.
+	server.render(ctx)
 
-	if len(valueFiles) > 0 {
-		chartStrValue += "\n---\nvalue_files:\n"
-		for _, file := range valueFiles {
-			chartStrValue += fmt.Sprintf("- %s\n", strings.TrimPrefix(file, fnprefix))
-		}
-	}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/data", httpHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Access-Control-Allow-Origin", devUIOrigin)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 
-	if len(chartVersions) > 0 {
-		chartStrValue += "\n---\nchart_versions:\n"
-		for _, file := range chartVersions {
-			chartStrValue += fmt.Sprintf("- %s\n", strings.TrimPrefix(file, fnprefix))
+		data := server.currentData()
+		if data.Error != nil && !server.watch {
+			// without watch mode, try again on each browser refresh until the problem is fixed.
+			data = server.render(r.Context())
 		}
-	}
 
-	releaseStr, err := yaml.Marshal(releaseOptions)
-	if err != nil {
-		return err
-	}
+		return json.NewEncoder(w).Encode(data)
+	}))
 
-	valuesStr, err := yaml.Marshal(values)
-	if err != nil {
-		return err
-	}
+	mux.HandleFunc("/objects", httpHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Access-Control-Allow-Origin", devUIOrigin)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 
-	fullValuesStr, err := yaml.Marshal(valuesToRender["Values"])
-	if err != nil {
-		return err
-	}
+		return json.NewEncoder(w).Encode(server.currentData().Objects)
+	}))
 
-	renderValuesStr, err := yaml.Marshal(valuesToRender)
-	if err != nil {
-		return err
-	}
+	mux.HandleFunc("/dependencies", httpHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Access-Control-Allow-Origin", devUIOrigin)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 
-	data := apiData{
-		Chart:        chartStrValue,
-		Release:      string(releaseStr),
-		Values:       string(valuesStr),
-		FullValues:   string(fullValuesStr),
-		RenderValues: string(renderValuesStr),
-	}
+		return json.NewEncoder(w).Encode(server.currentData().Dependencies)
+	}))
+
+	mux.HandleFunc("/value-sources", httpHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Access-Control-Allow-Origin", devUIOrigin)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		return json.NewEncoder(w).Encode(server.currentData().ValueSources)
+	}))
 
-	for cf := range chartFilesIter(chart) {
-		fv, ok := renderedTemplate[cf.FullPath]
-		if !ok {
-			// slog.Warn("cannot find rendered template", "template", cf.FullPath)
-			continue
+	mux.HandleFunc("/diff", httpHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Access-Control-Allow-Origin", devUIOrigin)
+		var diff apiDataDiff
+		var compareEnabled bool
+		err := server.withOptions(func(options renderOptions) error {
+			compareEnabled = isCompareEnabled(options)
+			if !compareEnabled {
+				return nil
+			}
+			var err error
+			diff, err = compareChart(options)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if !compareEnabled {
+			http.Error(w, "compare mode is not enabled, use the --compare-values, --compare-set or --compare-chart-version flags", http.StatusNotFound)
+			return nil
 		}
 
-		if strings.TrimSpace(fv) == "" {
-			continue
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		return json.NewEncoder(w).Encode(diff)
+	}))
+
+	mux.HandleFunc("/render", httpPostHandler(func(w http.ResponseWriter, r *http.Request) error {
+		var request apiRenderRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			return fmt.Errorf("error decoding render request: %w", err)
 		}
 
-		fileDesc := path.Join(cf.Path...)
-		if len(cf.Path) > 0 {
-			fileDesc += "/"
+		data, err := server.renderRequest(request)
+		if err != nil {
+			return err
 		}
-		fileDesc += cf.Filename
 
-		data.PreviewFiles = append(data.PreviewFiles, apiDataFile{
-			Filename: fileDesc,
-			Preview:  fv,
-		})
-	}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		return json.NewEncoder(w).Encode(data)
+	}))
 
-	mux := http.NewServeMux()
+	mux.HandleFunc("/chart-version", httpPostHandler(func(w http.ResponseWriter, r *http.Request) error {
+		if server.currentChartSource() == nil {
+			http.Error(w, "the chart version can only be changed when loading the chart from a repository", http.StatusBadRequest)
+			return nil
+		}
+
+		var request apiChartVersionRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			return fmt.Errorf("error decoding chart version request: %w", err)
+		}
+
+		data, err := server.switchChartVersion(ctx, request.Version)
+		if err != nil {
+			return err
+		}
 
-	mux.HandleFunc("/data", httpHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		return json.NewEncoder(w).Encode(data)
+	}))
+
+	mux.HandleFunc("/environment", httpPostHandler(func(w http.ResponseWriter, r *http.Request) error {
+		var request apiEnvironmentRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			return fmt.Errorf("error decoding environment request: %w", err)
+		}
+
+		if err := server.loadEnvironment(ctx, request.Environment); err != nil {
+			return err
+		}
+		data := server.render(ctx)
+		server.events.Publish(dataUpdatedEvent)
 
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		return json.NewEncoder(w).Encode(data)
 	}))
 
-	err = uiHandler(mux)
+	mux.Handle("/events", server.events)
+
+	err := uiHandler(mux)
 	if err != nil {
 		return err
 	}
 
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", httpPort))
+	// only local browsers can access the server.
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", httpPort))
 	if err != nil {
 		log.Fatalf("Failed to create listener: %v", err)
 	}
@@ -138,12 +193,229 @@ func runHTTP(ctx context.Context, httpPort int, chart *chart.Chart, valueFiles [
 	return http.Serve(listener, mux)
 }
 
+// loadEnvironment loads the options of the environment, replacing the current ones, and restarts watching the files
+// if enabled. On error, the current options are kept.
+func (s *httpServer) loadEnvironment(ctx context.Context, env string) error {
+	options, chartSource, closeOptions, err := s.loader(ctx, env)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stopWatch, err := s.startWatch(ctx, options)
+	if err != nil {
+		closeOptions()
+		return err
+	}
+
+	// the previous files are only closed after the options are replaced, and renders hold the lock while using them.
+	previousClose, previousStopWatch := s.closeOptions, s.stopWatch
+	s.options, s.chartSource = options, chartSource
+	s.closeOptions, s.stopWatch = closeOptions, stopWatch
+	if previousStopWatch != nil {
+		previousStopWatch()
+	}
+	if previousClose != nil {
+		previousClose()
+	}
+	if env != "" {
+		slog.InfoContext(ctx, "environment loaded", "environment", env)
+	}
+	return nil
+}
+
+// startWatch starts watching the chart files of the options if enabled, returning the function to stop it.
+func (s *httpServer) startWatch(ctx context.Context, options renderOptions) (context.CancelFunc, error) {
+	if !s.watch {
+		return nil, nil
+	}
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	err := watchChart(watchCtx, options, func() {
+		s.rerender(ctx)
+	})
+	if err != nil {
+		stopWatch()
+		return nil, fmt.Errorf("error watching chart files: %w", err)
+	}
+	slog.InfoContext(ctx, "watching chart files for changes", "chart", options.ChartFolder)
+	return stopWatch, nil
+}
+
+// close stops watching the files and removes the downloaded files.
+func (s *httpServer) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopWatch != nil {
+		s.stopWatch()
+	}
+	if s.closeOptions != nil {
+		s.closeOptions()
+	}
+}
+
+func (s *httpServer) currentChartSource() *repositoryChart {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.chartSource
+}
+
+// withOptions calls f with the current options, holding the lock so the chart files are not removed while f uses
+// them.
+func (s *httpServer) withOptions(f func(options renderOptions) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return f(s.options)
+}
+
+func (s *httpServer) currentData() apiData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data
+}
+
+// render reloads the chart and values from disk. On error, the data of the last successful render is kept, with
+// the error details added.
+func (s *httpServer) render(ctx context.Context) apiData {
+	var data apiData
+	err := s.withOptions(func(options renderOptions) error {
+		var err error
+		data, err = renderChart(options)
+		return err
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		slog.ErrorContext(ctx, "error rendering chart", "error", err)
+		data = s.data
+		data.Error = apiDataErrorFromError(err)
+	} else {
+		slog.InfoContext(ctx, "chart rendered")
+	}
+
+	s.data = data
+	return data
+}
+
+// rerender reloads the chart and values from disk and notifies the browsers.
+func (s *httpServer) rerender(ctx context.Context) {
+	s.render(ctx)
+	s.events.Publish(dataUpdatedEvent)
+}
+
+// renderRequest renders the chart using the values and release options sent by the browser. Nothing is written
+// to disk and the current data is not changed.
+func (s *httpServer) renderRequest(request apiRenderRequest) (apiData, error) {
+	values := map[string]any{}
+	if err := yaml.Unmarshal([]byte(request.Values), &values); err != nil {
+		return apiData{}, fmt.Errorf("failed to parse values: %w", err)
+	}
+	if values == nil {
+		values = map[string]any{}
+	}
+	// the edited values replace the value files and "--set" flags.
+	layers := []valuesLayer{{
+		Source:  editedValuesSource,
+		Content: []byte(request.Values),
+		Values:  copyValues(values).(map[string]any),
+	}}
+
+	var data apiData
+	err := s.withOptions(func(options renderOptions) error {
+		releaseOptions := options.ReleaseOptions
+		if strings.TrimSpace(request.Release) != "" {
+			if err := yaml.Unmarshal([]byte(request.Release), &releaseOptions); err != nil {
+				return fmt.Errorf("failed to parse release options: %w", err)
+			}
+		}
+
+		var err error
+		data, err = renderChartWithValues(options, values, layers, releaseOptions)
+		if err != nil {
+			data = apiData{Error: apiDataErrorFromError(err)}
+		}
+		return nil
+	})
+	return data, err
+}
+
+// switchChartVersion downloads another version of the chart from the repository and renders it with the same
+// values and release options.
+func (s *httpServer) switchChartVersion(ctx context.Context, version string) (apiData, error) {
+	chartSource := s.currentChartSource()
+	files, downloadedVersion, err := chartSource.Download(ctx, version)
+	if err != nil {
+		return apiData{}, fmt.Errorf("error loading chart version %s: %w", version, err)
+	}
+
+	err = func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.chartSource != chartSource {
+			// the environment was changed while downloading.
+			_ = files.Close()
+			return fmt.Errorf("the chart was changed while loading version %s", version)
+		}
+
+		options := s.options
+		options.ChartFolder = files.ChartPath()
+		options.ChartVersion = downloadedVersion
+		stopWatch, err := s.startWatch(ctx, options)
+		if err != nil {
+			_ = files.Close()
+			return err
+		}
+
+		// the previous version is only removed after the options and the watcher use the new one.
+		previousStopWatch := s.stopWatch
+		s.options, s.stopWatch = options, stopWatch
+		previousFiles := chartSource.SetFiles(files, downloadedVersion)
+		if previousStopWatch != nil {
+			previousStopWatch()
+		}
+		if previousFiles != nil {
+			if err := previousFiles.Close(); err != nil {
+				slog.WarnContext(ctx, "error removing chart files", "error", err)
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		return apiData{}, err
+	}
+
+	data := s.render(ctx)
+	s.events.Publish(dataUpdatedEvent)
+	return data, nil
+}
+
 func httpHandlerWithError(f func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		err := f(w, r)
 		if err != nil {
-			slog.ErrorContext(r.Context(), "http handler error: ", err)
+			slog.ErrorContext(r.Context(), "http handler error", "error", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 	}
 }
+
+// httpPostHandler handles POST requests with httpHandlerWithError, answering the CORS preflight requests of the UI
+// dev server and rejecting the other methods.
+func httpPostHandler(f func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+	return httpHandlerWithError(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Access-Control-Allow-Origin", devUIOrigin)
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		if r.Method == http.MethodOptions {
+			return nil
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return nil
+		}
+		return f(w, r)
+	})
+}
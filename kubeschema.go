@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// kubeSchemas loads Kubernetes JSON schemas from a local folder, using the layout of
+// https://github.com/yannh/kubernetes-json-schema (the one used by kubeconform), like
+// "v1.31.0-standalone-strict/deployment-apps-v1.json", or the built-in schemas for the kinds not found in the
+// folder, if enabled. Compiled schemas are cached between renders.
+type kubeSchemas struct {
+	dir     string
+	builtin *builtinKubeSchemas
+
+	mu     sync.Mutex
+	cache  map[string]*jsonschema.Schema
+	warned map[string]bool
+}
+
+func newKubeSchemas(dir string, builtin bool) *kubeSchemas {
+	ret := &kubeSchemas{
+		dir:    dir,
+		cache:  map[string]*jsonschema.Schema{},
+		warned: map[string]bool{},
+	}
+	if builtin {
+		ret.builtin = newBuiltinKubeSchemas()
+	}
+	return ret
+}
+
+// schemaFor returns the schema of the kind, and the file it was loaded from. It returns nil if no schema exists
+// for the kind.
+// The built-in schemas are of a single Kubernetes version, so a warning is logged (once per kube version) and the
+// version is added to the returned name when it doesn't match the kube version.
+func (k *kubeSchemas) schemaFor(kubeVersion string, gvk schema.GroupVersionKind) (*jsonschema.Schema, string, error) {
+	sch, filename, err := k.fileSchemaFor(kubeVersion, gvk)
+	if sch != nil || err != nil || k.builtin == nil {
+		return sch, filename, err
+	}
+	sch, err = k.builtin.schemaFor(gvk)
+	if sch == nil || err != nil {
+		return nil, "", err
+	}
+	builtinVersion := builtinKubeSchemasVersion()
+	if sameKubeMinorVersion(builtinVersion, kubeVersion) {
+		return sch, fmt.Sprintf("built-in Kubernetes %s schema", builtinVersion), nil
+	}
+	k.mu.Lock()
+	if !k.warned[kubeVersion] {
+		k.warned[kubeVersion] = true
+		slog.Warn("the built-in Kubernetes schemas are not of the kube version, set --kube-version to their version "+
+			"or use --kube-schema-dir", "builtinVersion", builtinVersion, "kubeVersion", kubeVersion)
+	}
+	k.mu.Unlock()
+	return sch, fmt.Sprintf("built-in Kubernetes %s schema, rendering for Kubernetes %s", builtinVersion,
+		kubeVersion), nil
+}
+
+// fileSchemaFor returns the schema of the kind from the schema folder.
+func (k *kubeSchemas) fileSchemaFor(kubeVersion string, gvk schema.GroupVersionKind) (*jsonschema.Schema, string, error) {
+	if k.dir == "" {
+		return nil, "", nil
+	}
+	for _, filename := range k.schemaFilenames(kubeVersion, gvk) {
+		if _, err := os.Stat(filename); err != nil {
+			continue
+		}
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if sch, ok := k.cache[filename]; ok {
+			return sch, filename, nil
+		}
+
+		absFilename, err := filepath.Abs(filename)
+		if err != nil {
+			return nil, filename, err
+		}
+		compiler := jsonschema.NewCompiler()
+		sch, err := compiler.Compile("file://" + filepath.ToSlash(absFilename))
+		if err != nil {
+			return nil, filename, fmt.Errorf("error compiling schema %s: %w", filename, err)
+		}
+		k.cache[filename] = sch
+		return sch, filename, nil
+	}
+	return nil, "", nil
+}
+
+// schemaFilenames returns the possible schema filenames for the kind, in order of preference.
+func (k *kubeSchemas) schemaFilenames(kubeVersion string, gvk schema.GroupVersionKind) []string {
+	kind := strings.ToLower(gvk.Kind)
+	name := fmt.Sprintf("%s-%s.json", kind, gvk.Version)
+	if gvk.Group != "" {
+		group, _, _ := strings.Cut(strings.ToLower(gvk.Group), ".")
+		name = fmt.Sprintf("%s-%s-%s.json", kind, group, gvk.Version)
+	}
+
+	var ret []string
+	if kubeVersion != "" {
+		ret = append(ret,
+			filepath.Join(k.dir, kubeVersion+"-standalone-strict", name),
+			filepath.Join(k.dir, kubeVersion+"-standalone", name),
+			filepath.Join(k.dir, kubeVersion, name),
+		)
+	}
+	ret = append(ret, filepath.Join(k.dir, name))
+	if gvk.Group != "" {
+		// layout of https://github.com/datreeio/CRDs-catalog
+		ret = append(ret, filepath.Join(k.dir, strings.ToLower(gvk.Group), fmt.Sprintf("%s_%s.json", kind, gvk.Version)))
+	}
+	return ret
+}
+
+// crdHeader is the part of a CustomResourceDefinition needed to validate custom resources.
+type crdHeader struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Group string `json:"group"`
+		Names struct {
+			Kind string `json:"kind"`
+		} `json:"names"`
+		Versions []struct {
+			Name   string `json:"name"`
+			Schema struct {
+				OpenAPIV3Schema map[string]any `json:"openAPIV3Schema"`
+			} `json:"schema"`
+		} `json:"versions"`
+	} `json:"spec"`
+}
+
+type crdSchema struct {
+	filename string
+	schema   *jsonschema.Schema
+}
+
+// loadCRDSchemas compiles the schemas of the custom resource definitions in the "crds" folder of the chart and its
+// subcharts. Invalid definitions are skipped with a warning.
+func loadCRDSchemas(cht *chart.Chart) map[schema.GroupVersionKind]crdSchema {
+	ret := map[schema.GroupVersionKind]crdSchema{}
+	for _, crd := range cht.CRDObjects() {
+		for _, manifest := range splitManifests(string(crd.File.Data)) {
+			var header crdHeader
+			if err := yaml.Unmarshal([]byte(manifest), &header); err != nil {
+				slog.Warn("error parsing CRD", "filename", crd.Filename, "error", err)
+				continue
+			}
+			if header.Kind != "CustomResourceDefinition" {
+				continue
+			}
+			for _, version := range header.Spec.Versions {
+				if version.Schema.OpenAPIV3Schema == nil {
+					continue
+				}
+				gvk := schema.GroupVersionKind{
+					Group:   header.Spec.Group,
+					Version: version.Name,
+					Kind:    header.Spec.Names.Kind,
+				}
+				sch, err := compileOpenAPISchema(gvk, version.Schema.OpenAPIV3Schema)
+				if err != nil {
+					slog.Warn("error compiling CRD schema", "filename", crd.Filename, "kind", gvk.String(), "error", err)
+					continue
+				}
+				ret[gvk] = crdSchema{filename: crd.Filename, schema: sch}
+			}
+		}
+	}
+	return ret
+}
+
+func compileOpenAPISchema(gvk schema.GroupVersionKind, openAPISchema map[string]any) (*jsonschema.Schema, error) {
+	schemaJSON, err := json.Marshal(normalizeOpenAPISchema(openAPISchema))
+	if err != nil {
+		return nil, err
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("file:///crds/%s/%s/%s.json", gvk.Group, gvk.Version, gvk.Kind)
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, doc); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(url)
+}
+
+// normalizeOpenAPISchema converts the OpenAPI "nullable" extension to JSON schema types.
+func normalizeOpenAPISchema(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		ret := make(map[string]any, len(v))
+		for key, item := range v {
+			ret[key] = normalizeOpenAPISchema(item)
+		}
+		if nullable, _ := ret["nullable"].(bool); nullable {
+			if typ, ok := ret["type"].(string); ok {
+				ret["type"] = []any{typ, "null"}
+			}
+		}
+		return ret
+	case []any:
+		ret := make([]any, len(v))
+		for idx, item := range v {
+			ret[idx] = normalizeOpenAPISchema(item)
+		}
+		return ret
+	default:
+		return value
+	}
+}
+
+// validateObjects validates the objects against the CRDs of the chart and the Kubernetes schemas, if a schema
+// folder was set. The errors are set in the objects.
+func validateObjects(objects []apiDataObject, cht *chart.Chart, schemas *kubeSchemas, caps *chartutil.Capabilities) {
+	crdSchemas := loadCRDSchemas(cht)
+	if len(crdSchemas) == 0 && schemas == nil {
+		return
+	}
+
+	kubeVersion := chartutil.DefaultCapabilities.KubeVersion.Version
+	if caps != nil {
+		kubeVersion = caps.KubeVersion.Version
+	}
+
+	for idx := range objects {
+		obj := &objects[idx]
+		if obj.APIVersion == "" || obj.Kind == "" {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(obj.APIVersion)
+		if err != nil {
+			continue
+		}
+		gvk := gv.WithKind(obj.Kind)
+
+		var sch *jsonschema.Schema
+		if crd, ok := crdSchemas[gvk]; ok {
+			sch, obj.Schema = crd.schema, crd.filename
+		} else if schemas != nil {
+			sch, obj.Schema, err = schemas.schemaFor(kubeVersion, gvk)
+			if err != nil {
+				slog.Warn("error loading Kubernetes schema", "kind", gvk.String(), "error", err)
+				continue
+			}
+		}
+		if sch == nil {
+			continue
+		}
+
+		obj.SchemaErrors = validateObject(sch, obj.Content)
+	}
+}
+
+func validateObject(sch *jsonschema.Schema, content string) []apiDataSchemaError {
+	contentJSON, err := yaml.YAMLToJSON([]byte(content))
+	if err != nil {
+		return []apiDataSchemaError{{Path: "$", Message: fmt.Sprintf("invalid YAML: %s", err)}}
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(contentJSON))
+	if err != nil {
+		return []apiDataSchemaError{{Path: "$", Message: fmt.Sprintf("invalid JSON: %s", err)}}
+	}
+
+	err = sch.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return []apiDataSchemaError{{Path: "$", Message: err.Error()}}
+	}
+	var ret []apiDataSchemaError
+	for leaf := range validationErrorLeaves(validationErr) {
+		ret = append(ret, apiDataSchemaError{
+			Path:    formatValuePath(leaf.InstanceLocation),
+			Message: leaf.ErrorKind.LocalizedString(schemaMessagePrinter),
+		})
+	}
+	return ret
+}
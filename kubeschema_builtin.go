@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// builtinKubeSchemas generates strict JSON schemas from the Kubernetes API types the tool was built with, so the
+// objects can be validated without downloading schemas. It is only used with --kube-schema-builtin. Unknown fields
+// and wrong types are reported, but required fields are not, as the Go types don't have that information. The types
+// are of a single Kubernetes version (builtinKubeSchemasVersion), whatever the kube version of the capabilities.
+type builtinKubeSchemas struct {
+	mu    sync.Mutex
+	cache map[schema.GroupVersionKind]*jsonschema.Schema
+}
+
+func newBuiltinKubeSchemas() *builtinKubeSchemas {
+	return &builtinKubeSchemas{
+		cache: map[schema.GroupVersionKind]*jsonschema.Schema{},
+	}
+}
+
+// schemaFor returns the schema of the kind, or nil if it is not a built-in kind.
+func (b *builtinKubeSchemas) schemaFor(gvk schema.GroupVersionKind) (*jsonschema.Schema, error) {
+	typ, ok := scheme.Scheme.AllKnownTypes()[gvk]
+	if !ok {
+		return nil, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if sch, ok := b.cache[gvk]; ok {
+		return sch, nil
+	}
+
+	defs := map[string]any{}
+	root := goTypeSchema(typ, defs)
+	root["$defs"] = defs
+	schemaJSON, err := json.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("file:///builtin/%s/%s/%s.json", gvk.Group, gvk.Version, gvk.Kind)
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, doc); err != nil {
+		return nil, err
+	}
+	sch, err := compiler.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling built-in schema of %s: %w", gvk.String(), err)
+	}
+	b.cache[gvk] = sch
+	return sch, nil
+}
+
+// builtinKubeSchemasVersion returns the Kubernetes version of the built-in API types, from the k8s.io/api module
+// version (v0.34.0 is Kubernetes v1.34.0).
+func builtinKubeSchemasVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "k8s.io/api" {
+				return strings.Replace(dep.Version, "v0.", "v1.", 1)
+			}
+		}
+	}
+	return "unknown"
+}
+
+// sameKubeMinorVersion returns whether both Kubernetes versions have the same major and minor version. Versions
+// that can't be parsed are never the same.
+func sameKubeMinorVersion(a, b string) bool {
+	va, err := version.ParseGeneric(a)
+	if err != nil {
+		return false
+	}
+	vb, err := version.ParseGeneric(b)
+	if err != nil {
+		return false
+	}
+	return va.Major() == vb.Major() && va.Minor() == vb.Minor()
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	// the types with a custom JSON format.
+	customJSONSchemas = map[reflect.Type]map[string]any{
+		reflect.TypeFor[resource.Quantity]():    {"type": []any{"string", "number"}},
+		reflect.TypeFor[intstr.IntOrString]():   {"type": []any{"string", "integer"}},
+		reflect.TypeFor[metav1.Time]():          {"type": []any{"string", "null"}},
+		reflect.TypeFor[metav1.MicroTime]():     {"type": []any{"string", "null"}},
+		reflect.TypeFor[metav1.Duration]():      {"type": []any{"string", "null"}},
+		reflect.TypeFor[runtime.RawExtension](): {},
+	}
+)
+
+// goTypeSchema returns the JSON schema of the Go type, following its JSON encoding. The named structs are added to
+// defs and referenced, as some API types are recursive. Null is accepted for all values, like Kubernetes does.
+func goTypeSchema(typ reflect.Type, defs map[string]any) map[string]any {
+	if sch, ok := customJSONSchemas[typ]; ok {
+		return sch
+	}
+	if typ.Kind() != reflect.Pointer && (typ.Implements(jsonMarshalerType) ||
+		reflect.PointerTo(typ).Implements(jsonMarshalerType)) {
+		// unknown custom format.
+		return map[string]any{}
+	}
+
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return goTypeSchema(typ.Elem(), defs)
+	case reflect.Bool:
+		return map[string]any{"type": []any{"boolean", "null"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": []any{"integer", "null"}}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": []any{"number", "null"}}
+	case reflect.String:
+		return map[string]any{"type": []any{"string", "null"}}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			// base64 encoded.
+			return map[string]any{"type": []any{"string", "null"}}
+		}
+		return map[string]any{
+			"type":  []any{"array", "null"},
+			"items": goTypeSchema(typ.Elem(), defs),
+		}
+	case reflect.Map:
+		return map[string]any{
+			"type":                 []any{"object", "null"},
+			"additionalProperties": goTypeSchema(typ.Elem(), defs),
+		}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return goStructSchema(typ, defs)
+		}
+		name := strings.NewReplacer("/", "_", ".", "_").Replace(typ.PkgPath() + "." + typ.Name())
+		if _, ok := defs[name]; !ok {
+			defs[name] = nil // set before the fields, for recursive types.
+			defs[name] = goStructSchema(typ, defs)
+		}
+		return map[string]any{"$ref": "#/$defs/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+// goStructSchema returns the schema of the struct, which doesn't allow unknown fields. The inline fields are
+// merged.
+func goStructSchema(typ reflect.Type, defs map[string]any) map[string]any {
+	properties := map[string]any{}
+	var addFields func(typ reflect.Type)
+	addFields = func(typ reflect.Type) {
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" && opts == "" {
+				continue
+			}
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if name == "" && (field.Anonymous || strings.Contains(opts, "inline")) &&
+				fieldType.Kind() == reflect.Struct {
+				addFields(fieldType)
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = goTypeSchema(field.Type, defs)
+		}
+	}
+	addFields(typ)
+	return map[string]any{
+		"type":                 []any{"object", "null"},
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestSameKubeMinorVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "v1.34.0", b: "v1.34.2", want: true},
+		{a: "v1.34.0", b: "1.34", want: true},
+		{a: "v1.34.0", b: "v1.29.3", want: false},
+		{a: "unknown", b: "v1.34.0", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if got := sameKubeMinorVersion(tt.a, tt.b); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestKubeSchemasBuiltinVersion(t *testing.T) {
+	schemas := newKubeSchemas("", true)
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	builtinVersion := builtinKubeSchemasVersion()
+
+	sch, name, err := schemas.schemaFor(builtinVersion, gvk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sch == nil || strings.Contains(name, "rendering for") {
+		t.Fatalf("expected the built-in schema without a version note, got %q", name)
+	}
+
+	// another kube version still validates, but the name says the versions differ.
+	sch, name, err = schemas.schemaFor("v1.20.0", gvk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sch == nil || !strings.Contains(name, "rendering for Kubernetes v1.20.0") {
+		t.Fatalf("expected the built-in schema with a version note, got %q", name)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"helm.sh/helm/v3/pkg/engine"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// clusterScopedKinds are the built-in Kubernetes kinds that are not namespaced. Lookups of these kinds ignore the
+// namespace, like Helm does when talking to a real cluster.
+var clusterScopedKinds = []string{
+	"APIService", "CSIDriver", "CSINode", "CertificateSigningRequest", "ClusterRole", "ClusterRoleBinding",
+	"CustomResourceDefinition", "IngressClass", "MutatingWebhookConfiguration", "Namespace", "Node",
+	"PersistentVolume", "PriorityClass", "RuntimeClass", "StorageClass", "ValidatingAdmissionPolicy",
+	"ValidatingAdmissionPolicyBinding", "ValidatingWebhookConfiguration", "VolumeAttachment",
+}
+
+// fixtureCluster is an in-memory Kubernetes cluster built from fixture files, used to answer the "lookup"
+// template function. All lookups are recorded so they can be shown in the browser.
+type fixtureCluster struct {
+	objects []*unstructured.Unstructured
+
+	mu      sync.Mutex
+	lookups []apiDataLookup
+}
+
+var _ engine.ClientProvider = (*fixtureCluster)(nil)
+
+// loadFixtureCluster loads the Kubernetes objects from files or folders containing YAML or JSON files. Files may
+// contain multiple documents, and "List" objects like the ones output by "kubectl get -o yaml" are expanded.
+func loadFixtureCluster(paths []string) (*fixtureCluster, error) {
+	ret := &fixtureCluster{}
+	for _, fixturePath := range paths {
+		err := filepath.WalkDir(fixturePath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if path != fixturePath && !slices.Contains([]string{".yaml", ".yml", ".json"}, filepath.Ext(path)) {
+				return nil
+			}
+			return ret.loadFile(path)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error loading lookup fixtures from %s: %w", fixturePath, err)
+		}
+	}
+	return ret, nil
+}
+
+func (c *fixtureCluster) loadFile(filename string) error {
+	bytes, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	for _, manifest := range splitManifests(string(bytes)) {
+		var obj map[string]any
+		if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", filename, err)
+		}
+		if obj == nil {
+			continue
+		}
+		u := &unstructured.Unstructured{Object: obj}
+		if u.IsList() {
+			err := u.EachListItem(func(item runtime.Object) error {
+				c.objects = append(c.objects, item.(*unstructured.Unstructured))
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to parse list in %s: %w", filename, err)
+			}
+			continue
+		}
+		if u.GetKind() == "" || u.GetAPIVersion() == "" {
+			return fmt.Errorf("object in %s has no apiVersion or kind", filename)
+		}
+		c.objects = append(c.objects, u)
+	}
+	return nil
+}
+
+// GetClientFor implements engine.ClientProvider.
+func (c *fixtureCluster) GetClientFor(apiVersion, kind string) (dynamic.NamespaceableResourceInterface, bool, error) {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, false, err
+	}
+	gvk := gv.WithKind(kind)
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	return &fixtureResource{
+		cluster: c,
+		gvk:     gvk,
+		gvr:     gvr,
+	}, !slices.Contains(clusterScopedKinds, kind), nil
+}
+
+// Lookups returns the lookups that were executed, in order.
+func (c *fixtureCluster) Lookups() []apiDataLookup {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.lookups)
+}
+
+func (c *fixtureCluster) addLookup(lookup apiDataLookup, found bool, result any) {
+	lookup.Found = found
+	if result != nil {
+		if resultStr, err := yaml.Marshal(result); err == nil {
+			lookup.Result = string(resultStr)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lookups = append(c.lookups, lookup)
+}
+
+// fixtureResource implements the parts of dynamic.NamespaceableResourceInterface used by the "lookup" function.
+type fixtureResource struct {
+	dynamic.NamespaceableResourceInterface
+
+	cluster   *fixtureCluster
+	gvk       schema.GroupVersionKind
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+func (r *fixtureResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return &fixtureResource{
+		cluster:   r.cluster,
+		gvk:       r.gvk,
+		gvr:       r.gvr,
+		namespace: namespace,
+	}
+}
+
+func (r *fixtureResource) Get(_ context.Context, name string, _ metav1.GetOptions, _ ...string) (*unstructured.Unstructured, error) {
+	lookup := r.lookup(name)
+	for obj := range r.matching() {
+		if obj.GetName() == name {
+			r.cluster.addLookup(lookup, true, obj.Object)
+			return obj.DeepCopy(), nil
+		}
+	}
+	r.cluster.addLookup(lookup, false, nil)
+	return nil, apierrors.NewNotFound(r.gvr.GroupResource(), name)
+}
+
+func (r *fixtureResource) List(_ context.Context, _ metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	list := &unstructured.UnstructuredList{
+		Object: map[string]any{
+			"apiVersion": r.gvk.GroupVersion().String(),
+			"kind":       r.gvk.Kind + "List",
+		},
+	}
+	for obj := range r.matching() {
+		list.Items = append(list.Items, *obj.DeepCopy())
+	}
+	r.cluster.addLookup(r.lookup(""), len(list.Items) > 0, list.UnstructuredContent())
+	return list, nil
+}
+
+func (r *fixtureResource) lookup(name string) apiDataLookup {
+	return apiDataLookup{
+		APIVersion: r.gvk.GroupVersion().String(),
+		Kind:       r.gvk.Kind,
+		Namespace:  r.namespace,
+		Name:       name,
+	}
+}
+
+// matching returns the fixture objects with the resource kind, in the resource namespace if set.
+func (r *fixtureResource) matching() iter.Seq[*unstructured.Unstructured] {
+	return func(yield func(*unstructured.Unstructured) bool) {
+		for _, obj := range r.cluster.objects {
+			if obj.GroupVersionKind() != r.gvk {
+				continue
+			}
+			if r.namespace != "" && obj.GetNamespace() != r.namespace {
+				continue
+			}
+			if !yield(obj) {
+				return
+			}
+		}
+	}
+}
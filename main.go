@@ -3,23 +3,24 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"slices"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/rrgmc/helm-render-ui/helm"
 	"github.com/urfave/cli/v3"
-	"helm.sh/helm/v3/pkg/chart"
-	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
-	"sigs.k8s.io/yaml"
+	"helm.sh/helm/v3/pkg/postrender"
 )
 
 func main() {
 	ctx := context.Background()
 	if err := run(ctx); err != nil {
 		slog.ErrorContext(ctx, "error running command", "error", err)
+		os.Exit(1)
 	}
 }
 
@@ -27,153 +28,477 @@ func run(ctx context.Context) error {
 	cmd := &cli.Command{
 		Name:      "helm-render-ui",
 		ArgsUsage: "[helm chart folder]",
-		Arguments: []cli.Argument{
-			&cli.StringArgs{
-				Name:      "helm-chart-folder",
-				UsageText: "A folder containing a Chart.yaml file",
-				Min:       1,
-				Max:       1,
-			},
-		},
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:  "repo",
-				Usage: "helm repository URL. If set, the folder name parameter will be used as the chart name",
-			},
-			&cli.StringFlag{
-				Name:  "chart-version",
-				Usage: "chart version (if downloading from repository)",
-			},
-			&cli.StringFlag{
-				Name:    "namespace",
-				Aliases: []string{"n"},
-				Usage:   "namespace",
-				Value:   "default",
-			},
-			&cli.StringFlag{
-				Name:    "release",
-				Aliases: []string{"r"},
-				Usage:   "release name",
-			},
-			&cli.StringSliceFlag{
-				Name:    "values",
-				Aliases: []string{"f"},
-				Usage:   "extra configuration values file name",
-			},
+		// the "--set" flags are parsed by Helm, which handles the commas itself.
+		DisableSliceFlagSeparator: true,
+		Arguments:                 chartArguments(),
+		Flags: append(renderFlags(),
 			&cli.IntFlag{
 				Name:    "http-port",
 				Aliases: []string{"p"},
 				Usage:   "http port",
 			},
 			&cli.BoolFlag{
-				Name:  "is-upgrade",
-				Usage: "sets upgrade mode",
-				Value: false,
+				Name:    "watch",
+				Aliases: []string{"w"},
+				Usage:   "watch the chart folder and value files, re-rendering on changes",
 			},
 			&cli.BoolFlag{
 				Name:   "dev-port",
 				Usage:  "dev http port",
 				Hidden: true,
 			},
-		},
+		),
 		Action: func(ctx context.Context, command *cli.Command) error {
-			chartRepo := command.String("repo")
-			chartFolder := command.StringArgs("helm-chart-folder")[0]
-
-			if strings.TrimSpace(chartFolder) == "" {
-				return fmt.Errorf("helm chart folder is required")
+			loader := func(ctx context.Context, env string) (renderOptions, *repositoryChart, func(), error) {
+				return loadRenderOptions(ctx, command, env)
 			}
-			var err error
 
-			var cht *chart.Chart
-			var chartVersions []string
-			if chartRepo != "" {
-				chartName := chartFolder
-				slog.InfoContext(ctx, "loading chart from repository",
-					"repo", chartRepo,
-					"chart", chartFolder,
-					"version", command.String("chart-version"))
-
-				repository, err := helm.LoadRepository(chartRepo)
-				if err != nil {
-					return err
-				}
-
-				latestChart, err := repository.GetChart(chartFolder, command.String("chart-version"))
-				if err != nil {
-					return err
-				}
-
-				latestChartFiles, err := latestChart.Download()
-				if err != nil {
-					return err
-				}
-				defer latestChartFiles.Close()
-
-				chartFolder = latestChartFiles.ChartPath()
-
-				for entry, err := range repository.ChartVersions(chartName, 20) {
-					if err != nil {
-						slog.Warn("error listing chart versions", "error", err)
-						break
-					}
-					var date string
-					if !entry.Created.IsZero() {
-						date = fmt.Sprintf(" [%s]", entry.Created.Format(time.RFC3339))
-					}
-					chartVersions = append(chartVersions, fmt.Sprintf("%s%s", entry.Version, date))
-				}
+			httpPort := command.Int("http-port")
+			if command.Bool("dev-port") {
+				httpPort = devHTTPPort
 			}
 
-			cht, err = loader.LoadDir(chartFolder)
-			if err != nil {
-				return fmt.Errorf("error loading chart from folder: %w", err)
-			}
+			return runHTTP(ctx, httpPort, loader, command.String("env"), command.Bool("watch"))
+		},
+		Commands: []*cli.Command{
+			renderCommand(),
+			exportCommand(),
+			cacheCommand(),
+		},
+	}
 
-			var displayValueFiles []string
+	return cmd.Run(ctx, os.Args)
+}
 
-			values := map[string]any{}
-			for _, valueFile := range command.StringSlice("values") {
-				currentMap := map[string]interface{}{}
+// chartArguments returns the chart argument, shared by all commands.
+func chartArguments() []cli.Argument {
+	return []cli.Argument{
+		&cli.StringArgs{
+			Name:      "helm-chart-folder",
+			UsageText: "A folder containing a Chart.yaml file, a chart archive (.tgz) file or URL, an OCI chart reference (oci://registry/path/chart:tag), or repo/chart for a repository added with 'helm repo add'. A folder named like a command must be passed as a path, like ./render",
+			// may be set by the environment.
+			Min: 0,
+			Max: 1,
+		},
+	}
+}
 
-				displayValueFiles = append(displayValueFiles, ensureRelativePath(strings.TrimPrefix(valueFile, chartFolder)))
+// renderFlags returns the flags used to load and render the chart, shared by all commands.
+func renderFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "env",
+			Aliases: []string{"e"},
+			Usage:   "environment from the " + projectConfigFilename + " config file",
+		},
+		&cli.StringFlag{
+			Name:  "config",
+			Usage: "config file with the environments (default: " + projectConfigFilename + " in the chart folder or its parents, up to the repository root)",
+		},
+		&cli.StringFlag{
+			Name:  "repo",
+			Usage: "helm repository URL, or the name of a repository added with 'helm repo add'. If set, the folder name parameter will be used as the chart name",
+		},
+		&cli.StringFlag{
+			Name:  "chart-version",
+			Usage: "chart version (if downloading from repository)",
+		},
+		&cli.StringFlag{
+			Name:    "username",
+			Usage:   "helm repository username",
+			Sources: cli.EnvVars("HELM_RENDER_UI_USERNAME"),
+		},
+		&cli.StringFlag{
+			Name:    "password",
+			Usage:   "helm repository password",
+			Sources: cli.EnvVars("HELM_RENDER_UI_PASSWORD"),
+		},
+		&cli.BoolFlag{
+			Name:    "pass-credentials",
+			Usage:   "pass the helm repository credentials to all domains",
+			Sources: cli.EnvVars("HELM_RENDER_UI_PASS_CREDENTIALS"),
+		},
+		&cli.StringFlag{
+			Name:    "cert-file",
+			Usage:   "identify the HTTPS client using this SSL certificate file",
+			Sources: cli.EnvVars("HELM_RENDER_UI_CERT_FILE"),
+		},
+		&cli.StringFlag{
+			Name:    "key-file",
+			Usage:   "identify the HTTPS client using this SSL key file",
+			Sources: cli.EnvVars("HELM_RENDER_UI_KEY_FILE"),
+		},
+		&cli.StringFlag{
+			Name:    "ca-file",
+			Usage:   "verify certificates of HTTPS-enabled servers using this CA bundle",
+			Sources: cli.EnvVars("HELM_RENDER_UI_CA_FILE"),
+		},
+		&cli.BoolFlag{
+			Name:    "insecure-skip-tls-verify",
+			Usage:   "skip TLS certificate checks for the helm repository",
+			Sources: cli.EnvVars("HELM_RENDER_UI_INSECURE_SKIP_TLS_VERIFY"),
+		},
+		&cli.StringFlag{
+			Name:    "registry-config",
+			Usage:   "path to the OCI registry config file (default: the Helm one, falling back to the Docker one)",
+			Sources: cli.EnvVars("HELM_REGISTRY_CONFIG"),
+		},
+		&cli.StringFlag{
+			Name:    "registry-username",
+			Usage:   "OCI registry username, instead of the one from the registry config file",
+			Sources: cli.EnvVars("HELM_RENDER_UI_REGISTRY_USERNAME"),
+		},
+		&cli.BoolFlag{
+			Name:  "registry-password-stdin",
+			Usage: "read the OCI registry password from stdin",
+		},
+		&cli.BoolFlag{
+			Name:  "plain-http",
+			Usage: "use insecure HTTP connections for the OCI registry",
+		},
+		cacheDirFlag(),
+		&cli.DurationFlag{
+			Name:  "index-ttl",
+			Usage: "how long the cached repository index files are used before being downloaded again",
+			Value: defaultIndexTTL,
+		},
+		&cli.BoolFlag{
+			Name:    "offline",
+			Usage:   "load the repository index files and charts only from the cache, and don't download the remote values schema references",
+			Sources: cli.EnvVars("HELM_RENDER_UI_OFFLINE"),
+		},
+		&cli.BoolFlag{
+			Name:    "no-cache",
+			Usage:   "don't cache the repository index files, downloaded charts and dependencies",
+			Sources: cli.EnvVars("HELM_RENDER_UI_NO_CACHE"),
+		},
+		&cli.StringFlag{
+			Name:    "namespace",
+			Aliases: []string{"n"},
+			Usage:   "namespace",
+			Value:   "default",
+		},
+		&cli.StringFlag{
+			Name:    "release",
+			Aliases: []string{"r"},
+			Usage:   "release name",
+		},
+		&cli.StringSliceFlag{
+			Name:    "values",
+			Aliases: []string{"f"},
+			Usage:   "extra configuration values file name",
+		},
+		&cli.StringSliceFlag{
+			Name:  "set",
+			Usage: "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "set-string",
+			Usage: "set STRING values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "set-file",
+			Usage: "set values from respective files specified via the command line (can specify multiple or separate values with commas: key1=path1,key2=path2)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "set-json",
+			Usage: "set JSON values on the command line (can specify multiple or separate values with commas: key1=jsonval1,key2=jsonval2)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "set-literal",
+			Usage: "set a literal STRING value on the command line",
+		},
+		&cli.StringSliceFlag{
+			Name:  "compare-values",
+			Usage: "value file name of the compared values. Enables compare mode, showing the difference between the objects",
+		},
+		&cli.StringSliceFlag{
+			Name:  "compare-set",
+			Usage: "like --set, for the compared values. Enables compare mode",
+		},
+		&cli.StringSliceFlag{
+			Name:  "compare-set-string",
+			Usage: "like --set-string, for the compared values. Enables compare mode",
+		},
+		&cli.StringSliceFlag{
+			Name:  "compare-set-file",
+			Usage: "like --set-file, for the compared values. Enables compare mode",
+		},
+		&cli.StringSliceFlag{
+			Name:  "compare-set-json",
+			Usage: "like --set-json, for the compared values. Enables compare mode",
+		},
+		&cli.StringSliceFlag{
+			Name:  "compare-set-literal",
+			Usage: "like --set-literal, for the compared values. Enables compare mode",
+		},
+		&cli.StringFlag{
+			Name:  "compare-chart-version",
+			Usage: "chart version to compare to, using the same values (if downloading from repository). Enables compare mode",
+		},
+		&cli.StringFlag{
+			Name:  "kube-version",
+			Usage: "Kubernetes version used for Capabilities.KubeVersion",
+		},
+		&cli.StringSliceFlag{
+			Name:    "api-versions",
+			Aliases: []string{"a"},
+			Usage:   "Kubernetes api versions used for Capabilities.APIVersions",
+		},
+		&cli.StringFlag{
+			Name:  "capabilities-file",
+			Usage: "YAML file with the Kubernetes capabilities ('kubeVersion' and 'apiVersions')",
+		},
+		&cli.StringSliceFlag{
+			Name:  "lookup-fixtures",
+			Usage: "file or folder with Kubernetes objects (like a 'kubectl get -o yaml' output) used to answer the 'lookup' template function",
+		},
+		&cli.StringFlag{
+			Name:  "kube-schema-dir",
+			Usage: "folder with Kubernetes JSON schemas (https://github.com/yannh/kubernetes-json-schema layout) used to validate the rendered objects",
+		},
+		&cli.BoolFlag{
+			Name:  "kube-schema-builtin",
+			Usage: "validate the rendered objects with schemas generated from the built-in Kubernetes API types, for the kinds not found in --kube-schema-dir. The schemas are of the Kubernetes version the tool was built with, whatever the kube version, and don't report missing required fields",
+		},
+		&cli.StringFlag{
+			Name:  "post-renderer",
+			Usage: "the path to an executable to be used for post rendering, like Helm's --post-renderer",
+		},
+		&cli.StringSliceFlag{
+			Name:  "post-renderer-args",
+			Usage: "an argument to the post-renderer (can specify multiple)",
+		},
+		&cli.BoolFlag{
+			Name:  "is-upgrade",
+			Usage: "sets upgrade mode",
+			Value: false,
+		},
+	}
+}
 
-				bytes, err := os.ReadFile(valueFile)
-				if err != nil {
-					return err
-				}
+// optionsLoader loads the render options for an environment of the project config file, or only from the flags if
+// the environment is empty.
+type optionsLoader func(ctx context.Context, env string) (renderOptions, *repositoryChart, func(), error)
 
-				if err := yaml.Unmarshal(bytes, &currentMap); err != nil {
-					return fmt.Errorf("failed to parse %s: %w", valueFile, err)
-				}
-				// Merge with the previous map
-				// values = mergeMaps(values, currentMap)
-				chartutil.CoalesceTables(values, currentMap)
-			}
+// loadRenderOptions loads the chart, downloading it if needed, and the render options from the flags and the
+// environment. Flags take precedence over the environment settings, and value files and "--set" flags are added
+// after the ones of the environment. The returned function removes the downloaded files.
+func loadRenderOptions(ctx context.Context, command *cli.Command, envName string) (renderOptions, *repositoryChart, func(), error) {
+	var closers []func()
+	closeAll := func() {
+		for _, closer := range slices.Backward(closers) {
+			closer()
+		}
+	}
+	fail := func(err error) (renderOptions, *repositoryChart, func(), error) {
+		closeAll()
+		return renderOptions{}, nil, nil, err
+	}
 
-			if err := chartutil.ProcessDependencies(cht, values); err != nil {
-				return err
-			}
+	var chartFolder string
+	if args := command.StringArgs("helm-chart-folder"); len(args) > 0 {
+		chartFolder = args[0]
+	}
+
+	config, err := loadProjectConfigForChart(command.String("config"), chartFolder)
+	if err != nil {
+		return fail(err)
+	}
+	var env projectEnvironment
+	if envName != "" {
+		if config == nil {
+			return fail(fmt.Errorf("environment '%s' requires a %s config file", envName, projectConfigFilename))
+		}
+		env, err = config.Environment(envName)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if strings.TrimSpace(chartFolder) == "" {
+		chartFolder = env.Chart
+	}
+	if strings.TrimSpace(chartFolder) == "" {
+		return fail(fmt.Errorf("helm chart folder is required"))
+	}
 
-			options := chartutil.ReleaseOptions{
-				Name:      command.String("release"),
-				Namespace: command.String("namespace"),
-				Revision:  1,
-				IsInstall: !command.Bool("is-upgrade"),
-				IsUpgrade: command.Bool("is-upgrade"),
+	chartRepo := flagOrEnvironment(command, "repo", env.Repo)
+	chartVersion := flagOrEnvironment(command, "chart-version", env.ChartVersion)
+	isArchive := false
+	if chartRepo == "" {
+		repoURL, chartName, tag, ok, err := splitOCIChartReference(chartFolder)
+		if err != nil {
+			return fail(err)
+		}
+		if ok {
+			chartRepo, chartFolder = repoURL, chartName
+			if chartVersion == "" {
+				chartVersion = tag
 			}
-			if options.Name == "" {
-				options.Name = cht.Metadata.Name
+		} else if repoName, chartName, ok := splitRepositoryChartName(chartFolder, helm.HasRepositoryEntry); ok {
+			chartRepo, chartFolder = repoName, chartName
+		} else if isArchive = isChartArchive(chartFolder); !isArchive {
+			if _, err := os.Stat(chartFolder); err != nil {
+				return fail(fmt.Errorf("error loading chart: %w", err))
 			}
+		}
+	}
+	repositoryOptions, err := repositoryOptionsFromFlags(command, chartRepo)
+	if err != nil {
+		return fail(err)
+	}
+	var chartSource *repositoryChart
+	if chartRepo != "" || isArchive {
+		if isArchive {
+			files, err := openChartArchive(ctx, chartFolder, repositoryOptions...)
+			if err != nil {
+				return fail(err)
+			}
+			closers = append(closers, func() { _ = files.Close() })
 
-			httpPort := command.Int("http-port")
-			if command.Bool("dev-port") {
-				httpPort = devHTTPPort
+			chartFolder = files.ChartPath()
+		} else {
+			chartSource, err = openRepositoryChart(ctx, chartRepo, chartFolder, chartVersion, repositoryOptions...)
+			if err != nil {
+				return fail(err)
 			}
+			closers = append(closers, func() { _ = chartSource.Close() })
+
+			chartFolder = chartSource.ChartPath()
+		}
+	}
 
-			return runHTTP(ctx, httpPort, cht, displayValueFiles, values, options, chartVersions)
+	capabilities, err := loadCapabilities(command.String("capabilities-file"),
+		flagOrEnvironment(command, "kube-version", env.KubeVersion),
+		slices.Concat(env.APIVersions, splitFlagValues(command.StringSlice("api-versions"))))
+	if err != nil {
+		return fail(err)
+	}
+
+	options := renderOptions{
+		ChartFolder: chartFolder,
+		Values:      env.mergeValuesOptions(valuesOptionsFromFlags(command, "")),
+		ReleaseOptions: chartutil.ReleaseOptions{
+			Name:      flagOrEnvironment(command, "release", env.Release),
+			Namespace: flagOrEnvironment(command, "namespace", env.Namespace),
+			Revision:  1,
+			IsInstall: !command.Bool("is-upgrade"),
+			IsUpgrade: command.Bool("is-upgrade"),
 		},
+		Dependencies:   newChartDependencies(repositoryOptions...),
+		Capabilities:   capabilities,
+		LookupFixtures: slices.Concat(env.LookupFixtures, splitFlagValues(command.StringSlice("lookup-fixtures"))),
+		Environment:    envName,
+		Environments:   config.EnvironmentNames(),
+		Offline:        command.Bool("offline"),
+	}
+	closers = append(closers, func() {
+		if err := options.Dependencies.Close(); err != nil {
+			slog.WarnContext(ctx, "error removing chart dependencies", "error", err)
+		}
+	})
+	if chartSource != nil {
+		options.ChartVersion = chartSource.Version()
+		options.ChartVersions = chartSource.Versions()
+	}
+	if compareValues := valuesOptionsFromFlags(command, "compare-"); !isEmptyValuesOptions(compareValues) {
+		options.CompareValues = &compareValues
 	}
+	if compareChartVersion := command.String("compare-chart-version"); compareChartVersion != "" {
+		if chartSource == nil {
+			return fail(fmt.Errorf("--compare-chart-version requires --repo"))
+		}
+		compareChartFiles, downloadedVersion, err := chartSource.Download(ctx, compareChartVersion)
+		if err != nil {
+			return fail(err)
+		}
+		closers = append(closers, func() { _ = compareChartFiles.Close() })
 
-	return cmd.Run(ctx, os.Args)
+		options.CompareChartFolder = compareChartFiles.ChartPath()
+		options.CompareChartVersion = downloadedVersion
+	}
+	if postRenderer := command.String("post-renderer"); postRenderer != "" {
+		options.PostRendererCommand = postRenderer
+		options.PostRendererArgs = command.StringSlice("post-renderer-args")
+		options.PostRenderer, err = postrender.NewExec(postRenderer, options.PostRendererArgs...)
+		if err != nil {
+			return fail(fmt.Errorf("error creating post-renderer: %w", err))
+		}
+	}
+	if kubeSchemaDir := command.String("kube-schema-dir"); kubeSchemaDir != "" || command.Bool("kube-schema-builtin") {
+		options.KubeSchemas = newKubeSchemas(kubeSchemaDir, command.Bool("kube-schema-builtin"))
+	}
+
+	return options, chartSource, closeAll, nil
+}
+
+// flagOrEnvironment returns the flag value if it was set, otherwise the environment value if not empty, otherwise
+// the flag default value.
+func flagOrEnvironment(command *cli.Command, name string, envValue string) string {
+	if command.IsSet(name) || envValue == "" {
+		return command.String(name)
+	}
+	return envValue
+}
+
+// repositoryOptionsFromFlags returns the authentication and TLS options of the helm repository or OCI registry.
+// Dependencies only use them if they are from the chart repository, which may be empty.
+func repositoryOptionsFromFlags(command *cli.Command, chartRepo string) ([]helm.RepositoryOption, error) {
+	// only set options override the settings of repositories added with "helm repo add".
+	options := []helm.RepositoryOption{helm.WithRepositoryOptionsURL(chartRepo)}
+	if command.IsSet("username") || command.IsSet("password") {
+		options = append(options, helm.WithRepositoryBasicAuth(command.String("username"), command.String("password")))
+	}
+	if command.IsSet("pass-credentials") {
+		options = append(options, helm.WithRepositoryPassCredentialsAll(command.Bool("pass-credentials")))
+	}
+	if command.IsSet("cert-file") || command.IsSet("key-file") || command.IsSet("ca-file") {
+		options = append(options, helm.WithRepositoryTLSClientConfig(command.String("cert-file"),
+			command.String("key-file"), command.String("ca-file")))
+	}
+	if command.IsSet("insecure-skip-tls-verify") {
+		options = append(options, helm.WithRepositoryInsecureSkipTLSVerify(command.Bool("insecure-skip-tls-verify")))
+	}
+	options = append(options,
+		helm.WithRegistryConfig(command.String("registry-config")),
+		helm.WithRegistryPlainHTTP(command.Bool("plain-http")),
+	)
+	if command.Bool("no-cache") {
+		if command.Bool("offline") {
+			return nil, fmt.Errorf("--offline can't be used with --no-cache")
+		}
+	} else {
+		cacheDir, err := cacheDirFromFlags(command)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, helm.WithRepositoryCache(helm.NewCache(cacheDir,
+			helm.WithCacheIndexTTL(command.Duration("index-ttl")),
+			helm.WithCacheOffline(command.Bool("offline")))))
+	}
+	if username := command.String("registry-username"); username != "" {
+		var password string
+		if command.Bool("registry-password-stdin") {
+			var err error
+			password, err = readStdinPassword()
+			if err != nil {
+				return nil, fmt.Errorf("error reading the registry password from stdin: %w", err)
+			}
+		}
+		options = append(options, helm.WithRegistryBasicAuth(username, password))
+	} else if command.Bool("registry-password-stdin") {
+		return nil, fmt.Errorf("--registry-password-stdin requires --registry-username")
+	}
+	return options, nil
 }
+
+// readStdinPassword reads the password from stdin once, as the options are loaded again when the environment
+// changes.
+var readStdinPassword = sync.OnceValues(func() (string, error) {
+	password, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(password), "\r\n"), nil
+})
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"cmp"
+	"path"
+	"slices"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+	"sigs.k8s.io/yaml"
+)
+
+// notesFileSuffix is the suffix of the chart notes template, which Helm doesn't install.
+const notesFileSuffix = "NOTES.txt"
+
+// objectHeader is the part of a Kubernetes object used to identify it.
+type objectHeader struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+}
+
+// parseObjects splits a rendered template file into its Kubernetes objects. Documents that are empty or contain
+// only comments are skipped, and documents that are not valid YAML are returned without identification.
+func parseObjects(template string, content string) []apiDataObject {
+	var ret []apiDataObject
+	for _, manifest := range splitManifests(content) {
+		if obj, ok := parseObject(template, manifest); ok {
+			ret = append(ret, obj)
+		}
+	}
+	return ret
+}
+
+// isManifestTemplate returns whether the rendered template has Kubernetes objects. Like Helm, the notes and the
+// partials (starting with "_") are skipped.
+func isManifestTemplate(name string) bool {
+	return !strings.HasSuffix(name, notesFileSuffix) && !strings.HasPrefix(path.Base(name), "_")
+}
+
+// parseObject parses a single YAML document. It returns false if the document is empty.
+func parseObject(template string, manifest string) (apiDataObject, bool) {
+	if isEmptyManifest(manifest) {
+		return apiDataObject{}, false
+	}
+	obj := apiDataObject{
+		Template: template,
+		Content:  ensureNewline(strings.TrimLeft(manifest, "\n")),
+	}
+	var header objectHeader
+	if err := yaml.Unmarshal([]byte(manifest), &header); err == nil {
+		obj.APIVersion = header.APIVersion
+		obj.Kind = header.Kind
+		obj.Name = header.Metadata.Name
+		obj.Namespace = header.Metadata.Namespace
+		if hooks, ok := header.Metadata.Annotations[release.HookAnnotation]; ok {
+			obj.Hooks = splitFlagValues([]string{hooks})
+		}
+	}
+	return obj, true
+}
+
+// sortObjects sorts the objects by kind, namespace and name.
+func sortObjects(objects []apiDataObject) {
+	slices.SortStableFunc(objects, func(a, b apiDataObject) int {
+		return cmp.Or(
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+}
+
+// isEmptyManifest returns whether the YAML document has only whitespace and comments.
+func isEmptyManifest(manifest string) bool {
+	for line := range strings.Lines(manifest) {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"strings"
+)
+
+// runPostRenderer pipes the non-hook manifests, in install order, through the post-renderer, in the same format
+// Helm uses. Hooks are not post-rendered by Helm.
+func runPostRenderer(options renderOptions, installOrder []apiDataObject) (*apiDataPostRender, error) {
+	var input bytes.Buffer
+	for _, obj := range installOrder {
+		fmt.Fprintf(&input, "---\n# Source: %s\n%s\n", obj.Source, strings.TrimSuffix(obj.Content, "\n"))
+	}
+	ret := &apiDataPostRender{
+		Command: strings.Join(append([]string{options.PostRendererCommand}, options.PostRendererArgs...), " "),
+		Input:   input.String(),
+	}
+
+	output, err := options.PostRenderer.Run(&input)
+	if err != nil {
+		return nil, fmt.Errorf("error while running post render on files: %w", err)
+	}
+	ret.Output = output.String()
+	ret.Diff = unifiedDiff("pre-render", "post-render", ret.Input, ret.Output)
+	for _, manifest := range splitManifests(ret.Output) {
+		source := manifestSource(manifest, "")
+		if obj, ok := parseObject(cmp.Or(source, "post-render"), manifest); ok {
+			obj.Source = source
+			ret.Objects = append(ret.Objects, obj)
+		}
+	}
+	return ret, nil
+}
+
+// manifestSource returns the template name from the "# Source:" comment of the manifest, which post-renderers
+// may keep.
+func manifestSource(manifest string, defaultSource string) string {
+	for line := range strings.Lines(manifest) {
+		if source, ok := strings.CutPrefix(strings.TrimSpace(line), "# Source: "); ok {
+			return source
+		}
+	}
+	return defaultSource
+}
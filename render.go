@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"iter"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/postrender"
+	"sigs.k8s.io/yaml"
+)
+
+// renderOptions holds everything needed to load a chart from disk and render it.
+type renderOptions struct {
+	ChartFolder   string
+	Values        values.Options
+	CompareValues *values.Options
+	// CompareChartFolder is the folder of the other chart version, when comparing chart versions.
+	CompareChartFolder  string
+	CompareChartVersion string
+	// Dependencies builds the dependencies missing from the "charts" folder.
+	Dependencies   *chartDependencies
+	ReleaseOptions chartutil.ReleaseOptions
+	Capabilities   *chartutil.Capabilities
+	LookupFixtures []string
+	KubeSchemas    *kubeSchemas
+	// PostRenderer, if set, receives the rendered manifests, like Helm's "--post-renderer".
+	PostRenderer        postrender.PostRenderer
+	PostRendererCommand string
+	PostRendererArgs    []string
+	ChartVersion        string
+	ChartVersions       []apiDataChartVersion
+	// Environment is the selected environment of the project config file.
+	Environment  string
+	Environments []string
+	// Offline disables downloading the remote "$ref" of the values schemas.
+	Offline bool
+}
+
+// renderChart loads the chart and the value files from disk and renders the chart templates.
+func renderChart(options renderOptions) (apiData, error) {
+	values, layers, err := loadValues(options)
+	if err != nil {
+		return apiData{}, err
+	}
+	return renderChartWithValues(options, values, layers, options.ReleaseOptions)
+}
+
+// renderChartWithValues loads the chart from disk and renders the chart templates using the passed values
+// instead of the value files. The layers are where the values were set, merged after the chart default values.
+func renderChartWithValues(options renderOptions, values map[string]any, layers []valuesLayer,
+	releaseOptions chartutil.ReleaseOptions) (apiData, error) {
+	cht, err := loadChart(options.ChartFolder, options.Dependencies)
+	if err != nil {
+		return apiData{}, err
+	}
+
+	// the dependency tree is evaluated before ProcessDependencies removes the disabled dependencies.
+	dependencies, err := dependencyTree(cht, values, "", true)
+	if err != nil {
+		return apiData{}, fmt.Errorf("error evaluating chart dependencies: %w", err)
+	}
+
+	if err := chartutil.ProcessDependencies(cht, values); err != nil {
+		return apiData{}, err
+	}
+
+	if releaseOptions.Name == "" {
+		releaseOptions.Name = cht.Metadata.Name
+	}
+
+	data, err := renderChartData(options, cht, values, layers, releaseOptions)
+	if err != nil {
+		return apiData{}, err
+	}
+	data.Dependencies = dependencies
+	return data, nil
+}
+
+// loadChart loads the chart from the folder, replacing subcharts that reference a local "file://"
+// repository with the current contents of the referenced folder, and adding the built dependencies that are
+// missing from the "charts" folder.
+func loadChart(chartFolder string, dependencies *chartDependencies) (*chart.Chart, error) {
+	cht, err := loader.LoadDir(chartFolder)
+	if err != nil {
+		return nil, fmt.Errorf("error loading chart from folder: %w", err)
+	}
+
+	for depFolder := range localDependencyFolders(chartFolder, cht.Metadata) {
+		if _, err := os.Stat(depFolder); err != nil {
+			// keep whatever was packaged in the "charts" folder
+			continue
+		}
+		dep, err := loadChart(depFolder, nil)
+		if err != nil {
+			return nil, err
+		}
+		deps := slices.DeleteFunc(cht.Dependencies(), func(c *chart.Chart) bool {
+			return c.Name() == dep.Name()
+		})
+		cht.SetDependencies(append(deps, dep)...)
+	}
+
+	builtDeps, err := dependencies.Load(chartFolder)
+	if err != nil {
+		return nil, err
+	}
+	cht.AddDependency(builtDeps...)
+
+	return cht, nil
+}
+
+func renderChartData(options renderOptions, cht *chart.Chart, values map[string]any, valueLayers []valuesLayer,
+	releaseOptions chartutil.ReleaseOptions) (apiData, error) {
+	fnprefix := fmt.Sprintf("%s/templates/", cht.Name())
+
+	// the schema is validated separately, to report all the errors.
+	valuesToRender, err := chartutil.ToRenderValuesWithSchemaValidation(cht, values, releaseOptions, options.Capabilities, true)
+	if err != nil {
+		return apiData{}, err
+	}
+
+	layers := append(chartValuesLayers(cht), valueLayers...)
+	valuesErrors := validateValuesSchema(cht, valuesToRender["Values"].(chartutil.Values), layers, options.Offline)
+
+	var cluster *fixtureCluster
+	if len(options.LookupFixtures) > 0 {
+		cluster, err = loadFixtureCluster(options.LookupFixtures)
+		if err != nil {
+			return apiData{}, err
+		}
+	}
+
+	var renderedTemplate map[string]string
+	if cluster != nil {
+		renderedTemplate, err = engine.RenderWithClientProvider(cht, valuesToRender, cluster)
+	} else {
+		renderedTemplate, err = engine.Render(cht, valuesToRender)
+	}
+	if err != nil {
+		return apiData{}, newTemplateError(cht, fmt.Errorf("cannot render template using engine: %w", err))
+	}
+
+	chartStr, err := yaml.Marshal(cht.Metadata)
+	if err != nil {
+		return apiData{}, err
+	}
+
+	chartStrValue := string(chartStr)
+
+	if valueFiles := displayValueFiles(options); len(valueFiles) > 0 {
+		chartStrValue += "\n---\nvalue_files:\n"
+		for _, file := range valueFiles {
+			chartStrValue += fmt.Sprintf("- %s\n", strings.TrimPrefix(file, fnprefix))
+		}
+	}
+
+	if setValues := displaySetValues(options); len(setValues) > 0 {
+		chartStrValue += "\n---\nset_values:\n"
+		for _, value := range setValues {
+			chartStrValue += fmt.Sprintf("- %q\n", value)
+		}
+	}
+
+	if len(options.ChartVersions) > 0 {
+		chartStrValue += "\n---\nchart_versions:\n"
+		for _, chartVersion := range options.ChartVersions {
+			chartStrValue += fmt.Sprintf("- %s", chartVersion.Version)
+			if chartVersion.Created != "" {
+				chartStrValue += fmt.Sprintf(" [%s]", chartVersion.Created)
+			}
+			chartStrValue += "\n"
+		}
+	}
+
+	releaseStr, err := yaml.Marshal(releaseOptions)
+	if err != nil {
+		return apiData{}, err
+	}
+
+	valuesStr, err := yaml.Marshal(values)
+	if err != nil {
+		return apiData{}, err
+	}
+
+	fullValuesStr, err := yaml.Marshal(valuesToRender["Values"])
+	if err != nil {
+		return apiData{}, err
+	}
+
+	renderValuesStr, err := yaml.Marshal(valuesToRender)
+	if err != nil {
+		return apiData{}, err
+	}
+
+	capabilitiesStr, err := yaml.Marshal(valuesToRender["Capabilities"])
+	if err != nil {
+		return apiData{}, err
+	}
+
+	data := apiData{
+		Chart:         chartStrValue,
+		Release:       string(releaseStr),
+		Values:        string(valuesStr),
+		FullValues:    string(fullValuesStr),
+		RenderValues:  string(renderValuesStr),
+		Capabilities:  string(capabilitiesStr),
+		ValuesErrors:  valuesErrors,
+		Compare:       options.CompareValues != nil,
+		ChartVersion:  options.ChartVersion,
+		ChartVersions: options.ChartVersions,
+		Environment:   options.Environment,
+		Environments:  options.Environments,
+		ValueSources:  valueSources(valuesToRender["Values"].(chartutil.Values), fullValuesStr, layers),
+	}
+
+	if cluster != nil {
+		data.Lookups = cluster.Lookups()
+	}
+
+	templateFiles := map[string]string{}
+	templateNames := map[string]string{}
+
+	for cf := range chartFilesIter(cht) {
+		fv, ok := renderedTemplate[cf.FullPath]
+		if !ok {
+			// slog.Warn("cannot find rendered template", "template", cf.FullPath)
+			continue
+		}
+
+		if strings.TrimSpace(fv) == "" {
+			continue
+		}
+
+		fileDesc := path.Join(cf.Path...)
+		if len(cf.Path) > 0 {
+			fileDesc += "/"
+		}
+		fileDesc += cf.Filename
+
+		data.PreviewFiles = append(data.PreviewFiles, apiDataFile{
+			Filename: fileDesc,
+			Preview:  fv,
+		})
+		if !isManifestTemplate(cf.FullPath) {
+			// the notes are only previewed.
+			continue
+		}
+		for _, obj := range parseObjects(fileDesc, fv) {
+			obj.Source = cf.FullPath
+			data.Objects = append(data.Objects, obj)
+		}
+		templateFiles[cf.FullPath] = fv
+		templateNames[cf.FullPath] = fileDesc
+	}
+	sortObjects(data.Objects)
+	validateObjects(data.Objects, cht, options.KubeSchemas, options.Capabilities)
+
+	data.Hooks, data.InstallOrder, err = sortManifests(templateFiles, templateNames, options.Capabilities)
+	if err != nil {
+		return apiData{}, err
+	}
+
+	if options.PostRenderer != nil {
+		data.PostRender, err = runPostRenderer(options, data.InstallOrder)
+		if err != nil {
+			return apiData{}, err
+		}
+		// the post-renderer may change the objects, so its output is validated too.
+		validateObjects(data.PostRender.Objects, cht, options.KubeSchemas, options.Capabilities)
+	}
+
+	return data, nil
+}
+
+// localDependencyFolders returns the folders of the chart dependencies that use a local "file://" repository.
+func localDependencyFolders(chartFolder string, metadata *chart.Metadata) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		if metadata == nil {
+			return
+		}
+		for _, dep := range metadata.Dependencies {
+			if !strings.HasPrefix(dep.Repository, "file://") {
+				continue
+			}
+			depFolder := filepath.FromSlash(strings.TrimPrefix(dep.Repository, "file://"))
+			if !filepath.IsAbs(depFolder) {
+				depFolder = filepath.Join(chartFolder, depFolder)
+			}
+			if !yield(filepath.Clean(depFolder)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	renderFormatYAML = "yaml"
+	renderFormatJSON = "json"
+)
+
+// postRenderFilename is the file name of the post-renderer output when writing to a folder.
+const postRenderFilename = "post-render"
+
+func renderCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "render",
+		Usage:     "render the chart without starting the server, writing the manifests to stdout or to a folder",
+		ArgsUsage: "[helm chart folder]",
+		Arguments: chartArguments(),
+		Flags: append(renderFlags(),
+			&cli.StringFlag{
+				Name:    "output-dir",
+				Aliases: []string{"d"},
+				Usage:   "write each template to a file in this folder instead of stdout",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "output format ('yaml' or 'json')",
+				Value: renderFormatYAML,
+				Validator: func(format string) error {
+					if !slices.Contains([]string{renderFormatYAML, renderFormatJSON}, format) {
+						return fmt.Errorf("invalid format '%s', must be '%s' or '%s'", format, renderFormatYAML, renderFormatJSON)
+					}
+					return nil
+				},
+			},
+		),
+		Action: func(ctx context.Context, command *cli.Command) error {
+			options, _, closeOptions, err := loadRenderOptions(ctx, command, command.String("env"))
+			if err != nil {
+				return err
+			}
+			defer closeOptions()
+
+			data, err := renderChart(options)
+			if err != nil {
+				return err
+			}
+
+			format := command.String("format")
+			if outputDir := command.String("output-dir"); outputDir != "" {
+				err = writeRenderFolder(outputDir, format, data)
+			} else {
+				err = writeRender(os.Stdout, format, data)
+			}
+			if err != nil {
+				return err
+			}
+
+			return renderValidationError(os.Stderr, data)
+		},
+	}
+}
+
+// writeRender writes all the manifests, without the notes, or the post-renderer output and the hooks if a post-renderer was used.
+func writeRender(w io.Writer, format string, data apiData) error {
+	if format == renderFormatJSON {
+		return writeObjectsJSON(w, renderedObjects(data))
+	}
+
+	if data.PostRender == nil {
+		files := map[string]string{}
+		for _, file := range data.PreviewFiles {
+			if isManifestTemplate(file.Filename) {
+				files[file.Filename] = file.Preview
+			}
+		}
+		_, err := io.WriteString(w, outputTemplate(files))
+		return err
+	}
+
+	for _, obj := range renderedObjects(data) {
+		header := "---\n"
+		if manifestSource(obj.Content, "") == "" {
+			// post-renderers may keep the "# Source:" comment.
+			header += fmt.Sprintf("# Source: %s\n", cmp.Or(obj.Source, obj.Template))
+		}
+		if _, err := io.WriteString(w, header+ensureNewline(obj.Content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRenderFolder writes each manifest template to a file in the folder, like "helm template --output-dir". The
+// output of the post-renderer, if used, is written to a separate file.
+func writeRenderFolder(folder string, format string, data apiData) error {
+	writeFile := func(name string, write func(io.Writer) error) error {
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid template file name: %s", name)
+		}
+		if format == renderFormatJSON {
+			name = strings.TrimSuffix(name, filepath.Ext(name)) + ".json"
+		}
+		filename := filepath.Join(folder, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			return err
+		}
+		f, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		if err := write(f); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("error writing %s: %w", filename, err)
+		}
+		return f.Close()
+	}
+
+	for _, file := range data.PreviewFiles {
+		if !isManifestTemplate(file.Filename) {
+			continue
+		}
+		err := writeFile(file.Filename, func(w io.Writer) error {
+			if format == renderFormatJSON {
+				return writeObjectsJSON(w, parseObjects(file.Filename, file.Preview))
+			}
+			_, err := io.WriteString(w, ensureNewline(file.Preview))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if data.PostRender != nil {
+		err := writeFile(postRenderFilename+".yaml", func(w io.Writer) error {
+			if format == renderFormatJSON {
+				return writeObjectsJSON(w, data.PostRender.Objects)
+			}
+			_, err := io.WriteString(w, data.PostRender.Output)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderedObjects returns the objects as they would be applied: the post-rendered objects followed by the hooks,
+// which Helm doesn't post-render, or all the objects if no post-renderer was used.
+func renderedObjects(data apiData) []apiDataObject {
+	if data.PostRender == nil {
+		return data.Objects
+	}
+	objects := slices.Clone(data.PostRender.Objects)
+	for _, obj := range data.Objects {
+		if len(obj.Hooks) > 0 {
+			objects = append(objects, obj)
+		}
+	}
+	return objects
+}
+
+// writeObjectsJSON writes the objects as a Kubernetes "List".
+func writeObjectsJSON(w io.Writer, objects []apiDataObject) error {
+	items := []json.RawMessage{}
+	for _, obj := range objects {
+		item, err := yaml.YAMLToJSON([]byte(obj.Content))
+		if err != nil {
+			return fmt.Errorf("error converting %s to JSON: %w", obj.Template, err)
+		}
+		items = append(items, item)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      items,
+	})
+}
+
+// renderValidationError prints the values and object schema errors, of the post-rendered objects if a post-renderer
+// was used, returning an error if there is any.
+func renderValidationError(w io.Writer, data apiData) error {
+	var errs []error
+	for _, valuesError := range data.ValuesErrors {
+		location := valuesError.Source
+		if valuesError.Line > 0 {
+			location = fmt.Sprintf("%s:%d", location, valuesError.Line)
+		}
+		if location != "" {
+			location = fmt.Sprintf(" (%s)", location)
+		}
+		errs = append(errs, fmt.Errorf("values %s %s: %s%s", valuesError.Chart, valuesError.Path,
+			valuesError.Message, location))
+	}
+	for _, obj := range renderedObjects(data) {
+		for _, schemaError := range obj.SchemaErrors {
+			errs = append(errs, fmt.Errorf("%s %s/%s (%s) %s: %s", obj.APIVersion, obj.Kind, obj.Name, obj.Template,
+				schemaError.Path, schemaError.Message))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	for _, err := range errs {
+		_, _ = fmt.Fprintln(w, err)
+	}
+	return fmt.Errorf("rendered with %d validation errors", len(errs))
+}
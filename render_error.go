@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// snippetContextLines is the number of template lines shown before and after the line of the error.
+const snippetContextLines = 3
+
+var (
+	// matches "execution error at (chart/templates/file.yaml:4:8): message" and
+	// "parse error at (chart/templates/file.yaml:1): message".
+	helmErrorLocationRegex = regexp.MustCompile(`\(([^():\s]+):(\d+)(?::(\d+))?\): (.*)$`)
+	// matches "template: chart/templates/file.yaml:1:13: executing ... : message".
+	goTemplateErrorLocationRegex = regexp.MustCompile(`template: ([^:\s]+):(\d+)(?::(\d+))?: (.*)$`)
+)
+
+// templateError is a render error with the location of the template that caused it.
+type templateError struct {
+	err    error
+	detail apiDataError
+}
+
+func (e *templateError) Error() string {
+	return e.err.Error()
+}
+
+func (e *templateError) Unwrap() error {
+	return e.err
+}
+
+// newTemplateError parses the template location from a Helm engine error, and extracts a snippet of the
+// template source around the failing line.
+func newTemplateError(cht *chart.Chart, err error) error {
+	detail := apiDataError{
+		Message: err.Error(),
+	}
+
+	var location []string
+	if m := helmErrorLocationRegex.FindStringSubmatch(err.Error()); m != nil {
+		location = m
+	} else if m := goTemplateErrorLocationRegex.FindStringSubmatch(err.Error()); m != nil {
+		location = m
+	}
+
+	if location != nil {
+		detail.Template = location[1]
+		detail.Line, _ = strconv.Atoi(location[2])
+		detail.Column, _ = strconv.Atoi(location[3])
+		detail.Reason = location[4]
+		if idx := strings.LastIndex(detail.Reason, ">: "); idx >= 0 {
+			// go template errors include the failing expression before the message.
+			detail.Reason = detail.Reason[idx+3:]
+		}
+		detail.Snippet = templateSnippet(cht, detail.Template, detail.Line)
+	}
+
+	return &templateError{
+		err:    err,
+		detail: detail,
+	}
+}
+
+// apiDataErrorFromError returns the error details to send to the browser.
+func apiDataErrorFromError(err error) *apiDataError {
+	var tErr *templateError
+	if errors.As(err, &tErr) {
+		detail := tErr.detail
+		detail.Message = err.Error()
+		return &detail
+	}
+	return &apiDataError{
+		Message: err.Error(),
+	}
+}
+
+// templateSnippet returns the lines around the passed line of the template source, with line numbers.
+func templateSnippet(cht *chart.Chart, templateName string, line int) string {
+	if line <= 0 {
+		return ""
+	}
+
+	var source string
+	for c := range chartAndDependencies(cht) {
+		for _, tmpl := range c.Templates {
+			if path.Join(c.ChartFullPath(), tmpl.Name) == templateName {
+				source = string(tmpl.Data)
+			}
+		}
+	}
+	if source == "" {
+		return ""
+	}
+
+	lines := strings.Split(source, "\n")
+	start := max(line-snippetContextLines, 1)
+	end := min(line+snippetContextLines, len(lines))
+
+	var snippet strings.Builder
+	for i := start; i <= end; i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		snippet.WriteString(fmt.Sprintf("%s %4d | %s\n", marker, i, lines[i-1]))
+	}
+	return snippet.String()
+}
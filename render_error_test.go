@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestNewTemplateError(t *testing.T) {
+	cht := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "c", Version: "1.0.0"},
+		Templates: []*chart.File{{
+			Name: "templates/cm.yaml",
+			Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Values.name }}\ndata:\n  a: b\n"),
+		}},
+	}
+
+	tests := []struct {
+		name string
+		err  string
+		want apiDataError
+	}{
+		{
+			name: "execution error",
+			err:  "execution error at (c/templates/cm.yaml:4:11): name is required",
+			want: apiDataError{
+				Template: "c/templates/cm.yaml",
+				Line:     4,
+				Column:   11,
+				Reason:   "name is required",
+				Snippet: "     1 | apiVersion: v1\n     2 | kind: ConfigMap\n     3 | metadata:\n" +
+					">    4 |   name: {{ .Values.name }}\n     5 | data:\n     6 |   a: b\n     7 | \n",
+			},
+		},
+		{
+			name: "parse error",
+			err:  "parse error at (c/templates/cm.yaml:1): unexpected \"}\" in operand",
+			want: apiDataError{
+				Template: "c/templates/cm.yaml",
+				Line:     1,
+				Reason:   "unexpected \"}\" in operand",
+				Snippet: ">    1 | apiVersion: v1\n     2 | kind: ConfigMap\n     3 | metadata:\n" +
+					"     4 |   name: {{ .Values.name }}\n",
+			},
+		},
+		{
+			name: "go template error",
+			err: "template: c/templates/cm.yaml:4:16: executing \"c/templates/cm.yaml\" at <.Values.name.first>: " +
+				"nil pointer evaluating interface {}.first",
+			want: apiDataError{
+				Template: "c/templates/cm.yaml",
+				Line:     4,
+				Column:   16,
+				Reason:   "nil pointer evaluating interface {}.first",
+				Snippet: "     1 | apiVersion: v1\n     2 | kind: ConfigMap\n     3 | metadata:\n" +
+					">    4 |   name: {{ .Values.name }}\n     5 | data:\n     6 |   a: b\n     7 | \n",
+			},
+		},
+		{
+			name: "unknown template",
+			err:  "execution error at (c/templates/missing.yaml:2:1): failed",
+			want: apiDataError{
+				Template: "c/templates/missing.yaml",
+				Line:     2,
+				Column:   1,
+				Reason:   "failed",
+			},
+		},
+		{
+			name: "no location",
+			err:  "chart requires kubeVersion: >=1.30.0",
+			want: apiDataError{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("error rendering chart: %w", newTemplateError(cht, errors.New(tt.err)))
+			got := apiDataErrorFromError(err)
+			want := tt.want
+			want.Message = "error rendering chart: " + tt.err
+			if *got != want {
+				t.Fatalf("expected %+v, got %+v", want, *got)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rrgmc/helm-render-ui/helm"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// maxChartVersions is the number of chart versions listed from the repository.
+const maxChartVersions = 20
+
+// repositoryChart is a chart downloaded from a Helm repository. Another version of the chart can be downloaded
+// while running, replacing the current one.
+type repositoryChart struct {
+	repository *helm.Repository
+	name       string
+	versions   []apiDataChartVersion
+
+	mu      sync.Mutex
+	version string
+	files   *helm.ChartFiles
+}
+
+// openRepositoryChart loads the repository from its URL or its "helm repo add" name, lists the chart versions and
+// downloads the requested version of the chart, or the latest one if empty.
+func openRepositoryChart(ctx context.Context, repoURL string, name string, version string,
+	options ...helm.RepositoryOption) (*repositoryChart, error) {
+	slog.InfoContext(ctx, "loading chart from repository",
+		"repo", repoURL,
+		"chart", name,
+		"version", version)
+
+	var repository *helm.Repository
+	var err error
+	if isRepositoryURL(repoURL) {
+		repository, err = helm.LoadRepository(repoURL, options...)
+	} else {
+		repository, err = helm.LoadRepositoryEntry(repoURL, options...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &repositoryChart{
+		repository: repository,
+		name:       name,
+	}
+
+	for entry, err := range repository.ChartVersions(name, maxChartVersions) {
+		if err != nil {
+			slog.Warn("error listing chart versions", "error", err)
+			break
+		}
+		chartVersion := apiDataChartVersion{
+			Version: entry.Version,
+		}
+		if !entry.Created.IsZero() {
+			chartVersion.Created = entry.Created.Format(time.RFC3339)
+		}
+		ret.versions = append(ret.versions, chartVersion)
+	}
+
+	files, downloadedVersion, err := ret.Download(ctx, version)
+	if err != nil {
+		_ = repository.Close()
+		return nil, err
+	}
+	ret.SetFiles(files, downloadedVersion)
+	return ret, nil
+}
+
+// isRepositoryURL returns whether the repository is an URL instead of the name of a repository added with
+// "helm repo add".
+func isRepositoryURL(repo string) bool {
+	return strings.Contains(repo, "://")
+}
+
+// splitRepositoryChartName splits a "repo/chart" reference to a chart of a repository added with "helm repo add".
+// Local folders take precedence, and the reference is only used if repositoryExists returns true for the repository
+// name, so a missing folder is reported as such.
+func splitRepositoryChartName(chart string, repositoryExists func(name string) bool) (string, string, bool) {
+	if _, err := os.Stat(chart); err == nil {
+		return "", "", false
+	}
+	if filepath.IsAbs(chart) || strings.HasPrefix(chart, ".") {
+		return "", "", false
+	}
+	repoName, chartName, ok := strings.Cut(chart, "/")
+	if !ok || repoName == "" || chartName == "" || strings.Contains(chartName, "/") || !repositoryExists(repoName) {
+		return "", "", false
+	}
+	return repoName, chartName, true
+}
+
+// splitOCIChartReference splits an "oci://registry/path/chart:tag" reference into the repository, chart name and tag,
+// which may be empty. References by digest ("chart@sha256:...") return an error, as the chart is loaded by tag.
+func splitOCIChartReference(ref string) (repoURL string, chartName string, tag string, ok bool, err error) {
+	if !registry.IsOCI(ref) {
+		return "", "", "", false, nil
+	}
+	idx := strings.LastIndex(ref, "/")
+	repoURL, chartName = ref[:idx], ref[idx+1:]
+	if repoURL == registry.OCIScheme+":/" || chartName == "" {
+		return "", "", "", false, nil
+	}
+	if strings.Contains(chartName, "@") {
+		return "", "", "", false, fmt.Errorf("OCI chart references by digest are not supported, use a tag: %s", ref)
+	}
+	chartName, tag, _ = strings.Cut(chartName, ":")
+	return repoURL, chartName, tag, true, nil
+}
+
+// isChartArchive returns whether the chart is a packaged chart archive file or URL.
+func isChartArchive(chart string) bool {
+	if strings.HasPrefix(chart, "http://") || strings.HasPrefix(chart, "https://") {
+		return true
+	}
+	st, err := os.Stat(chart)
+	return err == nil && !st.IsDir()
+}
+
+// openChartArchive expands the chart archive file, or downloads it if it is an URL. The folder is removed when the
+// returned files are closed.
+func openChartArchive(ctx context.Context, archive string, options ...helm.RepositoryOption) (*helm.ChartFiles, error) {
+	if !strings.HasPrefix(archive, "http://") && !strings.HasPrefix(archive, "https://") {
+		return helm.LoadChartArchive(archive)
+	}
+	slog.InfoContext(ctx, "downloading chart", "url", archive)
+	return helm.DownloadChartArchive(archive, options...)
+}
+
+// Download downloads a version of the chart to a temporary folder, which is removed when the returned files are
+// closed. It also returns the downloaded version, which is the latest one if version is empty.
+func (c *repositoryChart) Download(ctx context.Context, version string) (*helm.ChartFiles, string, error) {
+	cht, err := c.repository.GetChart(c.name, version)
+	if err != nil {
+		return nil, "", err
+	}
+
+	slog.InfoContext(ctx, "downloading chart", "chart", c.name, "version", cht.Chart().Version)
+
+	files, err := cht.Download()
+	if err != nil {
+		return nil, "", err
+	}
+	return files, cht.Chart().Version, nil
+}
+
+// SetFiles replaces the current version of the chart with the downloaded one, returning the previous files, which
+// the caller must close after nothing uses them anymore.
+func (c *repositoryChart) SetFiles(files *helm.ChartFiles, version string) *helm.ChartFiles {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := c.files
+	c.files, c.version = files, version
+	return previous
+}
+
+// ChartPath returns the folder of the current version of the chart.
+func (c *repositoryChart) ChartPath() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.files.ChartPath()
+}
+
+// Version returns the current version of the chart.
+func (c *repositoryChart) Version() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// Versions returns the latest versions of the chart available in the repository, newest first.
+func (c *repositoryChart) Versions() []apiDataChartVersion {
+	return c.versions
+}
+
+func (c *repositoryChart) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	if c.files != nil {
+		err = c.files.Close()
+		c.files = nil
+	}
+	if rerr := c.repository.Close(); rerr != nil && err == nil {
+		err = rerr
+	}
+	if err != nil {
+		return fmt.Errorf("error closing repository chart: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitOCIChartReference(t *testing.T) {
+	tests := []struct {
+		ref                 string
+		repoURL, chart, tag string
+		ok                  bool
+		err                 bool
+	}{
+		{
+			ref:     "oci://ghcr.io/myorg/charts/mychart:1.2.3",
+			repoURL: "oci://ghcr.io/myorg/charts", chart: "mychart", tag: "1.2.3", ok: true,
+		},
+		{
+			ref:     "oci://ghcr.io/myorg/charts/mychart",
+			repoURL: "oci://ghcr.io/myorg/charts", chart: "mychart", ok: true,
+		},
+		{
+			ref:     "oci://localhost:5000/mychart:1.0.0",
+			repoURL: "oci://localhost:5000", chart: "mychart", tag: "1.0.0", ok: true,
+		},
+		{ref: "oci://ghcr.io/myorg/charts/mychart@sha256:0123456789abcdef", err: true},
+		{ref: "oci://ghcr.io/myorg/charts/mychart:1.2.3@sha256:0123456789abcdef", err: true},
+		{ref: "oci://ghcr.io/myorg/charts/", ok: false},
+		{ref: "oci://mychart", ok: false},
+		{ref: "https://charts.example.com/mychart", ok: false},
+		{ref: "./mychart", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			repoURL, chart, tag, ok, err := splitOCIChartReference(tt.ref)
+			if (err != nil) != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if ok != tt.ok || repoURL != tt.repoURL || chart != tt.chart || tag != tt.tag {
+				t.Fatalf("expected (%q, %q, %q, %v), got (%q, %q, %q, %v)", tt.repoURL, tt.chart, tt.tag, tt.ok,
+					repoURL, chart, tag, ok)
+			}
+		})
+	}
+}
+
+func TestSplitRepositoryChartName(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "myrepo", "local"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	repositoryExists := func(name string) bool {
+		return name == "myrepo"
+	}
+
+	tests := []struct {
+		chart          string
+		repoName, name string
+		ok             bool
+	}{
+		{chart: "myrepo/mychart", repoName: "myrepo", name: "mychart", ok: true},
+		// an existing folder takes precedence.
+		{chart: "myrepo/local", ok: false},
+		{chart: "otherrepo/mychart", ok: false},
+		{chart: "myrepo/mychart/sub", ok: false},
+		{chart: "./myrepo/mychart", ok: false},
+		{chart: filepath.Join(dir, "myrepo", "mychart"), ok: false},
+		{chart: "mychart", ok: false},
+		{chart: "myrepo/", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chart, func(t *testing.T) {
+			repoName, name, ok := splitRepositoryChartName(tt.chart, repositoryExists)
+			if ok != tt.ok || repoName != tt.repoName || name != tt.name {
+				t.Fatalf("expected (%q, %q, %v), got (%q, %q, %v)", tt.repoName, tt.name, tt.ok, repoName, name, ok)
+			}
+		})
+	}
+}
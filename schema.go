@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+var schemaMessagePrinter = message.NewPrinter(language.English)
+
+// schemaRefTimeout is the timeout to download a remote "$ref" of a values schema, short so a slow host doesn't block
+// every render.
+const schemaRefTimeout = 5 * time.Second
+
+// validateValuesSchema validates the coalesced values against the values.schema.json of the chart and of all
+// subcharts, like Helm does, but returning every violation instead of failing on the first chart. The remote "$ref"
+// of the schemas are only downloaded if not offline.
+func validateValuesSchema(cht *chart.Chart, values map[string]any, layers []valuesLayer,
+	offline bool) []apiDataValuesError {
+	return validateChartValuesSchema(cht, values, nil, layers, offline)
+}
+
+func validateChartValuesSchema(cht *chart.Chart, values map[string]any, prefix []string, layers []valuesLayer,
+	offline bool) []apiDataValuesError {
+	var ret []apiDataValuesError
+	if cht.Schema != nil {
+		for _, valueError := range validateSingleSchema(cht.Schema, values, offline) {
+			valueError.Chart = cht.ChartFullPath()
+			valuePath := append(slices.Clone(prefix), valueError.path...)
+			valueError.Path = formatValuePath(valuePath)
+			valueError.Source, valueError.Line, _ = locateValue(layers, valuePath)
+			ret = append(ret, valueError.apiDataValuesError)
+		}
+	}
+
+	for _, subchart := range cht.Dependencies() {
+		raw, exists := values[subchart.Name()]
+		if !exists || raw == nil {
+			// No values provided for this subchart; nothing to validate
+			continue
+		}
+		subchartPath := append(slices.Clone(prefix), subchart.Name())
+
+		subchartValues, ok := raw.(map[string]any)
+		if !ok {
+			ret = append(ret, apiDataValuesError{
+				Chart:   subchart.ChartFullPath(),
+				Path:    formatValuePath(subchartPath),
+				Message: fmt.Sprintf("invalid type for values: expected object (map), got %T", raw),
+			})
+			continue
+		}
+		ret = append(ret, validateChartValuesSchema(subchart, subchartValues, subchartPath, layers, offline)...)
+	}
+
+	return ret
+}
+
+type schemaValueError struct {
+	apiDataValuesError
+	path []string
+}
+
+func validateSingleSchema(schemaJSON []byte, values map[string]any, offline bool) (ret []schemaValueError) {
+	defer func() {
+		if r := recover(); r != nil {
+			ret = []schemaValueError{{apiDataValuesError: apiDataValuesError{
+				Message: fmt.Sprintf("unable to validate schema: %s", r),
+			}}}
+		}
+	}()
+
+	schemaErr := func(err error) []schemaValueError {
+		return []schemaValueError{{apiDataValuesError: apiDataValuesError{
+			Message: fmt.Sprintf("invalid schema: %s", err),
+		}}}
+	}
+
+	schema, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	if err != nil {
+		return schemaErr(err)
+	}
+
+	loader := jsonschema.SchemeURLLoader{
+		"file": jsonschema.FileLoader{},
+	}
+	if !offline {
+		httpLoader := (*chartutil.HTTPURLLoader)(&http.Client{
+			Timeout: schemaRefTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{},
+			},
+		})
+		loader["http"] = httpLoader
+		loader["https"] = httpLoader
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.UseLoader(loader)
+	if err := compiler.AddResource("file:///values.schema.json", schema); err != nil {
+		return schemaErr(err)
+	}
+	validator, err := compiler.Compile("file:///values.schema.json")
+	if err != nil {
+		return schemaErr(err)
+	}
+
+	err = validator.Validate(values)
+	if err == nil {
+		return nil
+	}
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return schemaErr(err)
+	}
+	for leaf := range validationErrorLeaves(validationErr) {
+		ret = append(ret, schemaValueError{
+			apiDataValuesError: apiDataValuesError{
+				Message: leaf.ErrorKind.LocalizedString(schemaMessagePrinter),
+			},
+			path: leaf.InstanceLocation,
+		})
+	}
+	return ret
+}
+
+// validationErrorLeaves yields the errors that have no causes, which are the actual violations.
+func validationErrorLeaves(err *jsonschema.ValidationError) func(yield func(*jsonschema.ValidationError) bool) {
+	return func(yield func(*jsonschema.ValidationError) bool) {
+		var walk func(e *jsonschema.ValidationError) bool
+		walk = func(e *jsonschema.ValidationError) bool {
+			if len(e.Causes) == 0 {
+				return yield(e)
+			}
+			for _, cause := range e.Causes {
+				if !walk(cause) {
+					return false
+				}
+			}
+			return true
+		}
+		walk(err)
+	}
+}
+
+// formatValuePath formats the path as a JSON path, like "$.image.pullSecrets[0]".
+func formatValuePath(valuePath []string) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, item := range valuePath {
+		if _, err := strconv.Atoi(item); err == nil {
+			sb.WriteString(fmt.Sprintf("[%s]", item))
+		} else {
+			sb.WriteString(".")
+			sb.WriteString(item)
+		}
+	}
+	return sb.String()
+}
@@ -1,7 +1,7 @@
 import React from "react";
 import Editor from "react-simple-code-editor";
 import Preview from "./preview";
-//import debounce from "lodash.debounce";
+import debounce from "lodash.debounce";
 import { Tab, Tabs, TabList, TabPanel } from "react-tabs";
 import { highlight, languages } from "prismjs/components/prism-core";
 
@@ -11,6 +11,29 @@ import "prismjs/themes/prism.css";
 import "prismjs/components/prism-clike";
 import "prismjs/components/prism-yaml";
 
+const previewModes = {
+  files: "Files",
+  objects: "Objects",
+  installOrder: "Install Order",
+  hooks: "Hooks",
+  diff: "Diff",
+  postRender: "Post Renderer",
+};
+
+// diffStatusClass is the CSS class of each object diff status.
+const diffStatusClass = {
+  added: "diff__object--added",
+  removed: "diff__object--removed",
+  changed: "diff__object--changed",
+  unchanged: "diff__object--unchanged",
+};
+
+// objectSource returns the object content with the "# Source:" comment, unless the post-renderer kept it.
+const objectSource = (obj) => /^# Source: /m.test(obj.content) ? obj.content : `# Source: ${obj.source || obj.template}\n${obj.content}`;
+
+// exportedData is set in pages written by the "export" command, which are opened without a server.
+const exportedData = window.__HELM_RENDER_UI_DATA__;
+
 type Props = {
   apiURL: string,
 };
@@ -27,12 +50,178 @@ export default class HelmTemplatePreview extends React.Component<Props> {
       rawRelease: "",
       rawCapabilities: "",
       renderedTemplateFiles: [],
+      lookups: [],
+      valuesErrors: [],
+      dependencies: [],
+      valueSources: [],
+      selectedValueLine: 0,
+      objects: [],
+      installOrder: [],
+      hooks: [],
+      compare: false,
+      postRender: null,
+      chartVersion: "",
+      chartVersions: [],
+      environment: "",
+      environments: [],
+      diff: null,
+      previewMode: "files",
       renderError: "",
+      renderErrorDetail: null,
     };
+
+    this.requestRender = debounce(this.requestRender.bind(this), 500);
   }
 
   componentDidMount() {
+    if (exportedData) {
+      this.applyData(exportedData.data);
+      this.setRenderError(exportedData.data.error);
+      this.setState({ diff: exportedData.diff || null });
+      return;
+    }
+
     this.updateHelmRender();
+
+    // the server sends an event when the chart is rendered again (watch mode)
+    this.events = new EventSource(`${this.props.apiURL}/events`);
+    this.events.addEventListener("updated", () => this.updateHelmRender());
+  }
+
+  componentWillUnmount() {
+    if (this.events) {
+      this.events.close();
+    }
+    this.requestRender.cancel();
+  }
+
+  onValuesChange(rawValues) {
+    this.setState({ rawValues: rawValues });
+    this.requestRender(rawValues, this.state.rawRelease);
+  }
+
+  onReleaseChange(rawRelease) {
+    this.setState({ rawRelease: rawRelease });
+    this.requestRender(this.state.rawValues, rawRelease);
+  }
+
+  // requestRender renders the chart on the server using the edited values and release, without changing any file.
+  requestRender(rawValues, rawRelease) {
+    fetch(`${this.props.apiURL}/render`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ values: rawValues, release: rawRelease }),
+    })
+      .then((res) => {
+        if (!res.ok) {
+          throw res;
+        }
+        return res.json();
+      })
+      .then((data) => {
+        if (data.error) {
+          // keep the last successful render visible
+          this.setRenderError(data.error);
+          return;
+        }
+        this.setState({
+          rawChart: data.chart,
+          rawValuesFull: data.fullValues,
+          rawRenderValues: data.renderValues,
+          rawCapabilities: data.capabilities,
+          renderedTemplateFiles: data.previewFiles || [],
+          lookups: data.lookups || [],
+          valuesErrors: data.valuesErrors || [],
+          dependencies: data.dependencies || [],
+          valueSources: data.valueSources || [],
+          objects: data.objects || [],
+          installOrder: data.installOrder || [],
+          hooks: data.hooks || [],
+          postRender: data.postRender || null,
+        });
+        this.setRenderError(null);
+      })
+      .catch((error) =>
+        error
+          .text()
+          .then((errorMessage) => this.setState({ renderError: errorMessage, renderErrorDetail: null }))
+      );
+  }
+
+  // onChartVersionChange downloads another version of the chart from the repository and renders it. The new data
+  // is loaded when the server sends the "updated" event.
+  onChartVersionChange(version) {
+    this.setState({ chartVersion: version });
+    fetch(`${this.props.apiURL}/chart-version`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ version: version }),
+    })
+      .then((res) => {
+        if (!res.ok) {
+          throw res;
+        }
+      })
+      .catch((error) =>
+        error
+          .text()
+          .then((errorMessage) => this.setState({ renderError: errorMessage, renderErrorDetail: null }))
+      );
+  }
+
+  // onEnvironmentChange renders the chart with the settings of another environment of the project config file. The
+  // new data is loaded when the server sends the "updated" event.
+  onEnvironmentChange(environment) {
+    this.setState({ environment: environment });
+    fetch(`${this.props.apiURL}/environment`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ environment: environment }),
+    })
+      .then((res) => {
+        if (!res.ok) {
+          throw res;
+        }
+      })
+      .catch((error) =>
+        error
+          .text()
+          .then((errorMessage) => this.setState({ renderError: errorMessage, renderErrorDetail: null }))
+      );
+  }
+
+  // setRenderError shows the structured render error sent by the server, or hides it if null.
+  setRenderError(error) {
+    this.setState({
+      renderError: error ? error.message : "",
+      renderErrorDetail: error,
+    });
+  }
+
+  // applyData shows the data of a render.
+  applyData(data) {
+    this.setState({
+      rawChart: data.chart,
+      rawRelease: data.release,
+      rawValues: data.values,
+      rawValuesFull: data.fullValues,
+      rawRenderValues: data.renderValues,
+      rawCapabilities: data.capabilities,
+      renderedTemplateFiles: data.previewFiles || [],
+      lookups: data.lookups || [],
+      valuesErrors: data.valuesErrors || [],
+      dependencies: data.dependencies || [],
+      valueSources: data.valueSources || [],
+      objects: data.objects || [],
+      installOrder: data.installOrder || [],
+      hooks: data.hooks || [],
+      compare: !!data.compare,
+      postRender: data.postRender || null,
+      chartVersion: data.chartVersion || "",
+      chartVersions: data.chartVersions || [],
+      environment: data.environment || "",
+      environments: data.environments || [],
+    });
   }
 
   updateHelmRender() {
@@ -46,23 +235,19 @@ export default class HelmTemplatePreview extends React.Component<Props> {
     const renderTemplate = (res) => {
       res
         .json()
-        .then((data) =>
-          this.setState({
-              rawChart: data.chart,
-              rawRelease: data.release,
-              rawValues: data.values,
-              rawValuesFull: data.fullValues,
-              rawRenderValues: data.renderValues,
-              renderedTemplateFiles: data.previewFiles,
-              renderError: "",
-          })
-        );
+        .then((data) => {
+          this.applyData(data);
+          this.setRenderError(data.error);
+          if (data.compare) {
+            this.updateDiff();
+          }
+        });
     };
 
     const renderError = (error) => {
       error
         .text()
-        .then((errorMessage) => this.setState({ renderError: errorMessage }));
+        .then((errorMessage) => this.setState({ renderError: errorMessage, renderErrorDetail: null }));
     };
 
     fetch(`${this.props.apiURL}/data`, {
@@ -73,6 +258,82 @@ export default class HelmTemplatePreview extends React.Component<Props> {
       .catch(renderError);
   }
 
+  // updateDiff loads the difference between the values and the compared values (compare mode).
+  updateDiff() {
+    fetch(`${this.props.apiURL}/diff`, {
+      method: "GET",
+    })
+      .then((res) => {
+        if (!res.ok) {
+          throw res;
+        }
+        return res.json();
+      })
+      .then((diff) => this.setState({ diff: diff }))
+      .catch((error) =>
+        error
+          .text()
+          .then((errorMessage) => this.setState({ diff: { error: { message: errorMessage }, objects: [] } }))
+      );
+  }
+
+  // onFullValuesCursor selects the line of the full values at the cursor, to show where its value was set.
+  onFullValuesCursor(e) {
+    const textarea = e.target;
+    const line = textarea.value.substring(0, textarea.selectionStart).split("\n").length;
+    this.setState({ selectedValueLine: line });
+  }
+
+  // renderValueSource renders where the value at the selected line of the full values was set, and the values it
+  // overrode.
+  renderValueSource() {
+    const source = this.state.valueSources.find((s) => s.fullValuesLine === this.state.selectedValueLine);
+    if (!source) {
+      return <div className="value-source__empty">Select a value to see where it was set.</div>;
+    }
+    return <div>
+      <div className="value-source__path">{source.path}</div>
+      <div className="value-source__value">{source.value}</div>
+      <div className="value-source__source">
+        {source.source ? `set in ${source.source}${source.line ? `:${source.line}` : ""}` : "source not found"}
+      </div>
+      {source.overrides && <div className="value-source__overrides">
+        <div>overrides:</div>
+        {source.overrides.slice().reverse().map((override, idx) => <div key={`override-${idx}`} className="value-source__override">
+          {override.value} <span className="value-source__source">
+            from {override.source}{override.line ? `:${override.line}` : ""}
+          </span>
+        </div>)}
+      </div>}
+    </div>;
+  }
+
+  // renderDependencies renders the dependency tree, with the tag or condition that enabled or disabled each
+  // dependency.
+  renderDependencies(dependencies) {
+    return <ul className="dependencies__list">
+      {dependencies.map((dep) => <li key={`dep-${dep.path}`} className="dependencies__item">
+        <div className={dep.enabled ? "dependencies__name" : "dependencies__name dependencies__name--disabled"}>
+          {dep.alias ? `${dep.alias} (${dep.name})` : dep.name} {dep.chartVersion || dep.version}
+          {dep.enabled ? "" : " [disabled]"}
+        </div>
+        <div className="dependencies__detail">
+          {dep.reason ? (dep.enabled ? "enabled by " : "disabled by ") + dep.reason : (dep.enabled ? "enabled by default" : "")}
+        </div>
+        {dep.missing && <div className="dependencies__warning">no subchart matching version {dep.version} was found</div>}
+        {(dep.warnings || []).map((warning, idx) => <div key={`warning-${idx}`} className="dependencies__warning">{warning}</div>)}
+        {dep.version && <div className="dependencies__detail">version: {dep.version}</div>}
+        {dep.repository && <div className="dependencies__detail">repository: {dep.repository}</div>}
+        {dep.condition && <div className="dependencies__detail">condition: {dep.condition}</div>}
+        {dep.tags && <div className="dependencies__detail">tags: {dep.tags.join(", ")}</div>}
+        {(dep.importValues || []).map((iv, idx) => <div key={`import-${idx}`} className="dependencies__detail">
+          import-values: {iv.child} &rarr; {iv.parent}
+        </div>)}
+        {dep.dependencies && this.renderDependencies(dep.dependencies)}
+      </li>)}
+    </ul>;
+  }
+
   render() {
     const style = {
       whiteSpace: "pre",
@@ -93,10 +354,43 @@ export default class HelmTemplatePreview extends React.Component<Props> {
         )
         .join("\n");
 
+    const diffHighlighter = (code) =>
+      code
+        .split("\n")
+        .map((line) => {
+          const escaped = line.replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;");
+          if (line.startsWith("+")) {
+            return `<span class="diff__line--added">${escaped}</span>`;
+          } else if (line.startsWith("-")) {
+            return `<span class="diff__line--removed">${escaped}</span>`;
+          }
+          return escaped;
+        })
+        .join("\n");
+
     return (
       <div className="app">
         <div className="navbar">
           <h1 className="navbar__title">Helm Render UI</h1>
+          {this.state.environments.length > 0 && !exportedData && <select
+            className="navbar__environment"
+            value={this.state.environment}
+            onChange={(e) => this.onEnvironmentChange(e.target.value)}
+          >
+            <option value="">(no environment)</option>
+            {this.state.environments.map((env) => <option key={`env-${env}`} value={env}>{env}</option>)}
+          </select>}
+          {this.state.chartVersions.length > 0 && !exportedData && <select
+            className="navbar__chart-version"
+            value={this.state.chartVersion}
+            onChange={(e) => this.onChartVersionChange(e.target.value)}
+          >
+            {!this.state.chartVersions.some((v) => v.version === this.state.chartVersion) &&
+              <option value={this.state.chartVersion}>{this.state.chartVersion}</option>}
+            {this.state.chartVersions.map((v) => <option key={`cv-${v.version}`} value={v.version}>
+              {v.version}{v.created ? ` (${v.created.substring(0, 10)})` : ""}
+            </option>)}
+          </select>}
         </div>
         <div className="container">
           <div className="input">
@@ -108,6 +402,10 @@ export default class HelmTemplatePreview extends React.Component<Props> {
                   <Tab>Full Values</Tab>
                   <Tab>Release</Tab>
                   <Tab>Render Values</Tab>
+                  <Tab>Capabilities</Tab>
+                  <Tab>Lookups</Tab>
+                  <Tab>Validation{this.state.valuesErrors.length > 0 ? ` (${this.state.valuesErrors.length})` : ""}</Tab>
+                  <Tab>Dependencies</Tab>
                 </TabList>
                   <TabPanel>
                       <Editor
@@ -121,6 +419,7 @@ export default class HelmTemplatePreview extends React.Component<Props> {
                 <TabPanel>
                   <Editor
                     value={this.state.rawValues}
+                    onValueChange={exportedData ? undefined : (code) => this.onValuesChange(code)}
                     highlight={highlighter}
                     padding={padding}
                     style={style}
@@ -128,17 +427,27 @@ export default class HelmTemplatePreview extends React.Component<Props> {
                   />
                 </TabPanel>
                   <TabPanel>
-                      <Editor
-                          value={this.state.rawValuesFull}
-                          highlight={highlighter}
-                          padding={padding}
-                          style={style}
-                          className="input__values__editor editor"
-                      />
+                      <div className="full-values">
+                          <div className="full-values__editor">
+                              <Editor
+                                  value={this.state.rawValuesFull}
+                                  onClick={(e) => this.onFullValuesCursor(e)}
+                                  onKeyUp={(e) => this.onFullValuesCursor(e)}
+                                  highlight={highlighter}
+                                  padding={padding}
+                                  style={style}
+                                  className="input__values__editor editor"
+                              />
+                          </div>
+                          <div className="value-source">
+                              {this.renderValueSource()}
+                          </div>
+                      </div>
                   </TabPanel>
                 <TabPanel>
                   <Editor
                     value={this.state.rawRelease}
+                    onValueChange={exportedData ? undefined : (code) => this.onReleaseChange(code)}
                     highlight={highlighter}
                     padding={padding}
                     style={style}
@@ -154,11 +463,67 @@ export default class HelmTemplatePreview extends React.Component<Props> {
                           className="input__values__editor editor"
                       />
                   </TabPanel>
+                  <TabPanel>
+                      <Editor
+                          value={this.state.rawCapabilities}
+                          highlight={highlighter}
+                          padding={padding}
+                          style={style}
+                          className="input__capabilities__editor editor"
+                      />
+                  </TabPanel>
+                  <TabPanel>
+                      <div className="lookups">
+                          {this.state.lookups.length === 0 && <div className="lookups__empty">No lookups were executed.</div>}
+                          {this.state.lookups.map((lookup, idx) => <div key={`lookup-${idx}`} className="lookups__item">
+                              <div className={lookup.found ? "lookups__call" : "lookups__call lookups__call--missing"}>
+                                  lookup "{lookup.apiVersion}" "{lookup.kind}" "{lookup.namespace || ""}" "{lookup.name || ""}"
+                                  {lookup.found ? "" : " (not found)"}
+                              </div>
+                              {lookup.result && <Preview
+                                  value={lookup.result}
+                                  highlight={highlighter}
+                                  padding={padding}
+                                  style={style}
+                                  className="preview__highlighted"
+                              />}
+                          </div>)}
+                      </div>
+                  </TabPanel>
+                  <TabPanel>
+                      <div className="validation">
+                          {this.state.valuesErrors.length === 0 && <div className="validation__empty">Values match the chart schemas.</div>}
+                          {this.state.valuesErrors.map((valuesError, idx) => <div key={`validation-${idx}`} className="validation__item">
+                              <div className="validation__path">{valuesError.chart}: {valuesError.path}</div>
+                              <div className="validation__message">{valuesError.message}</div>
+                              {valuesError.source && <div className="validation__source">
+                                  set in {valuesError.source}{valuesError.line ? `:${valuesError.line}` : ""}
+                              </div>}
+                          </div>)}
+                      </div>
+                  </TabPanel>
+                  <TabPanel>
+                      <div className="dependencies">
+                          {this.state.dependencies.length === 0 && <div className="dependencies__empty">The chart has no dependencies.</div>}
+                          {this.renderDependencies(this.state.dependencies)}
+                      </div>
+                  </TabPanel>
               </Tabs>
             </div>
           </div>
           <div className="preview">
-            <Tabs>
+            <div className="preview__modes">
+              {Object.keys(previewModes).filter((mode) => (mode !== "diff" || this.state.compare) && (mode !== "postRender" || this.state.postRender)).map((mode) => (
+                <button
+                  key={`mode-${mode}`}
+                  className={this.state.previewMode === mode ? "preview__mode preview__mode--selected" : "preview__mode"}
+                  onClick={() => this.setState({ previewMode: mode })}
+                >
+                  {previewModes[mode]}
+                </button>
+              ))}
+            </div>
+            {this.state.previewMode === "files" && <Tabs>
               <TabList>
                   { this.state.renderedTemplateFiles.map(file => <Tab key={`l-${file.filename}`}>{file.filename}</Tab>) }
               </TabList>
@@ -171,15 +536,181 @@ export default class HelmTemplatePreview extends React.Component<Props> {
                         className="preview__highlighted"
                     />
                 </TabPanel>) }
-            </Tabs>
-
+            </Tabs>}
+            {this.state.previewMode === "objects" && <Tabs>
+              <TabList>
+                  { this.state.objects.map((obj, idx) => <Tab key={`lo-${idx}`} title={`${obj.apiVersion} ${obj.namespace || ""}`}>
+                      {obj.kind || "(unknown)"}/{obj.name}{obj.hooks ? ` [${obj.hooks.join(",")}]` : ""}
+                      {obj.schemaErrors ? ` (${obj.schemaErrors.length} errors)` : ""}
+                  </Tab>) }
+              </TabList>
+                { this.state.objects.map((obj, idx) => <TabPanel key={`po-${idx}`}>
+                    {obj.schemaErrors && <div className="schema-errors">
+                        <div className="schema-errors__source">{obj.schema}</div>
+                        { obj.schemaErrors.map((schemaError, eidx) => <div key={`se-${eidx}`} className="schema-errors__item">
+                            {schemaError.path}: {schemaError.message}
+                        </div>) }
+                    </div>}
+                    <Preview
+                        value={objectSource(obj)}
+                        highlight={highlighter}
+                        padding={padding}
+                        style={style}
+                        className="preview__highlighted"
+                    />
+                </TabPanel>) }
+            </Tabs>}
+            {this.state.previewMode === "installOrder" && <Tabs>
+              <TabList>
+                  { this.state.installOrder.map((obj, idx) => <Tab key={`li-${idx}`} title={`${obj.apiVersion} ${obj.namespace || ""}`}>
+                      {idx + 1}. {obj.kind}/{obj.name}
+                  </Tab>) }
+              </TabList>
+                { this.state.installOrder.map((obj, idx) => <TabPanel key={`pi-${idx}`}>
+                    <Preview
+                        value={objectSource(obj)}
+                        highlight={highlighter}
+                        padding={padding}
+                        style={style}
+                        className="preview__highlighted"
+                    />
+                </TabPanel>) }
+            </Tabs>}
+            {this.state.previewMode === "diff" && this.state.diff && <div className="diff">
+              <div className="diff__header">
+                  --- {this.state.diff.left}<br/>+++ {this.state.diff.right}
+                  {this.state.diff.error && <div className="diff__error">{this.state.diff.error.message}</div>}
+              </div>
+              <Tabs>
+                <TabList>
+                    {this.state.diff.defaultValues && <Tab>values.yaml (defaults)</Tab>}
+                    { this.state.diff.objects.map((obj, idx) => <Tab key={`ld-${idx}`} title={`${obj.apiVersion} ${obj.namespace || ""}`}>
+                        <span className={diffStatusClass[obj.status]}>{obj.kind}/{obj.name} ({obj.status})</span>
+                    </Tab>) }
+                </TabList>
+                  {this.state.diff.defaultValues && <TabPanel>
+                      <div className="diff__values">
+                          {(this.state.diff.defaultValuesChanges || []).map((change) => <div key={`vc-${change.path}`} className={diffStatusClass[change.status]}>
+                              {change.path} ({change.status}): {change.left || ""}{change.status === "changed" ? " => " : ""}{change.right || ""}
+                          </div>)}
+                      </div>
+                      <Preview
+                          value={this.state.diff.defaultValues}
+                          highlight={diffHighlighter}
+                          padding={padding}
+                          style={style}
+                          className="preview__highlighted"
+                      />
+                  </TabPanel>}
+                  { this.state.diff.objects.map((obj, idx) => <TabPanel key={`pd-${idx}`}>
+                      <Preview
+                          value={obj.diff || obj.left || obj.right}
+                          highlight={obj.diff ? diffHighlighter : highlighter}
+                          padding={padding}
+                          style={style}
+                          className="preview__highlighted"
+                      />
+                  </TabPanel>) }
+              </Tabs>
+            </div>}
+            {this.state.previewMode === "postRender" && this.state.postRender && <div className="post-render">
+              <div className="post-render__command">{this.state.postRender.command}</div>
+              <Tabs>
+                <TabList>
+                    <Tab>Diff</Tab>
+                    <Tab>Input</Tab>
+                    <Tab>Output</Tab>
+                    { (this.state.postRender.objects || []).map((obj, idx) => <Tab key={`lpr-${idx}`} title={`${obj.apiVersion} ${obj.namespace || ""}`}>
+                        {obj.kind || "(unknown)"}/{obj.name}
+                        {obj.schemaErrors ? ` (${obj.schemaErrors.length} errors)` : ""}
+                    </Tab>) }
+                </TabList>
+                  <TabPanel>
+                      <Preview
+                          value={this.state.postRender.diff || "# no changes"}
+                          highlight={diffHighlighter}
+                          padding={padding}
+                          style={style}
+                          className="preview__highlighted"
+                      />
+                  </TabPanel>
+                  <TabPanel>
+                      <Preview
+                          value={this.state.postRender.input}
+                          highlight={highlighter}
+                          padding={padding}
+                          style={style}
+                          className="preview__highlighted"
+                      />
+                  </TabPanel>
+                  <TabPanel>
+                      <Preview
+                          value={this.state.postRender.output}
+                          highlight={highlighter}
+                          padding={padding}
+                          style={style}
+                          className="preview__highlighted"
+                      />
+                  </TabPanel>
+                  { (this.state.postRender.objects || []).map((obj, idx) => <TabPanel key={`ppr-${idx}`}>
+                      {obj.schemaErrors && <div className="schema-errors">
+                          <div className="schema-errors__source">{obj.schema}</div>
+                          { obj.schemaErrors.map((schemaError, eidx) => <div key={`pse-${eidx}`} className="schema-errors__item">
+                              {schemaError.path}: {schemaError.message}
+                          </div>) }
+                      </div>}
+                      <Preview
+                          value={objectSource(obj)}
+                          highlight={highlighter}
+                          padding={padding}
+                          style={style}
+                          className="preview__highlighted"
+                      />
+                  </TabPanel>) }
+              </Tabs>
+            </div>}
+            {this.state.previewMode === "hooks" && <Tabs>
+              <TabList>
+                  { this.state.hooks.map((hookEvent) => <Tab key={`lh-${hookEvent.event}`}>
+                      {hookEvent.event} ({hookEvent.hooks.length})
+                  </Tab>) }
+              </TabList>
+                { this.state.hooks.map((hookEvent) => <TabPanel key={`ph-${hookEvent.event}`}>
+                    <div className="hooks">
+                        { hookEvent.hooks.map((hook, idx) => <div key={`hook-${idx}`} className="hooks__item">
+                            <div className="hooks__title">
+                                {idx + 1}. {hook.kind}/{hook.name} (weight: {hook.weight}, delete policy: {hook.deletePolicies.join(",")}
+                                {hook.defaultDeletePolicy ? " [default]" : ""})
+                            </div>
+                            <Preview
+                                value={`# Source: ${hook.template}\n${hook.content}`}
+                                highlight={highlighter}
+                                padding={padding}
+                                style={style}
+                                className="preview__highlighted"
+                            />
+                        </div>) }
+                    </div>
+                </TabPanel>) }
+            </Tabs>}
           </div>
         </div>
         <div
           className="render-error"
           style={this.state.renderError === "" ? { display: "none" } : {}}
         >
-          {this.state.renderError}
+          {this.state.renderErrorDetail && this.state.renderErrorDetail.template ? (
+            <div>
+              <div className="render-error__location">
+                {this.state.renderErrorDetail.template}:{this.state.renderErrorDetail.line}
+                {this.state.renderErrorDetail.column ? `:${this.state.renderErrorDetail.column}` : ""}
+              </div>
+              <div className="render-error__reason">{this.state.renderErrorDetail.reason}</div>
+              <pre className="render-error__snippet">{this.state.renderErrorDetail.snippet}</pre>
+            </div>
+          ) : (
+            this.state.renderError
+          )}
         </div>
       </div>
     );
@@ -9,15 +9,30 @@ import (
 	"path"
 	"runtime"
 	"slices"
+	"sort"
 	"strings"
 
 	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/releaseutil"
 )
 
 func ensureRelativePath(path string) string {
 	return strings.TrimLeft(path, "/")
 }
 
+// splitFlagValues splits comma-separated flag values.
+func splitFlagValues(values []string) []string {
+	var ret []string
+	for _, value := range values {
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				ret = append(ret, item)
+			}
+		}
+	}
+	return ret
+}
+
 func formatHelmFilename(ch *chart.Chart, name string) string {
 	name = strings.TrimPrefix(name, ch.Name())
 	name = strings.TrimPrefix(name, "/templates")
@@ -87,6 +102,35 @@ func chartFilesIter(ch *chart.Chart) iter.Seq[chartIterData] {
 	}
 }
 
+// splitManifests splits a multi-document YAML into its documents, keeping the order.
+func splitManifests(content string) []string {
+	manifests := releaseutil.SplitManifests(content)
+	keys := slices.Collect(maps.Keys(manifests))
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var ret []string
+	for _, key := range keys {
+		ret = append(ret, manifests[key])
+	}
+	return ret
+}
+
+// chartAndDependencies yields the chart and all of its dependencies, recursively.
+func chartAndDependencies(ch *chart.Chart) iter.Seq[*chart.Chart] {
+	return func(yield func(*chart.Chart) bool) {
+		if !yield(ch) {
+			return
+		}
+		for _, dep := range ch.Dependencies() {
+			for c := range chartAndDependencies(dep) {
+				if !yield(c) {
+					return
+				}
+			}
+		}
+	}
+}
+
 func ensureNewline(s string) string {
 	if !strings.HasSuffix(s, "\n") {
 		return s + "\n"
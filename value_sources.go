@@ -0,0 +1,96 @@
+package main
+
+import (
+	"iter"
+	"slices"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// valueSources returns where each leaf of the coalesced values was set, using the values layers in merge order: the
+// last layer that sets the leaf set the final value, and it overrode the values of the previous ones. The global
+// values of a chart are also looked up for its subcharts. fullValues is the YAML of the coalesced values, used to
+// find the line of each leaf.
+func valueSources(values map[string]any, fullValues []byte, layers []valuesLayer) []apiDataValueSource {
+	// the YAML documents are parsed once, to find the lines of all the leafs.
+	docs := make([]*yamlv3.Node, len(layers))
+	for idx, layer := range layers {
+		if layer.Content != nil {
+			docs[idx] = parseYAMLDocument(layer.Content)
+		}
+	}
+	fullValuesDoc := parseYAMLDocument(fullValues)
+
+	var ret []apiDataValueSource
+	for valuePath, value := range valueLeaves(nil, values) {
+		source := apiDataValueSource{
+			Path:  formatValuePath(valuePath),
+			Value: formatValueJSON(value),
+		}
+		source.FullValuesLine, _ = yamlNodePathLine(fullValuesDoc, valuePath)
+
+		var setters []apiDataValueOverride
+		for idx, layer := range layers {
+			layerPath, layerValue, ok := layerValueAtPath(layer, valuePath)
+			if !ok {
+				continue
+			}
+			setter := apiDataValueOverride{
+				Value:  formatValueJSON(layerValue),
+				Source: layer.Source,
+			}
+			setter.Line, _ = yamlNodePathLine(docs[idx], layerPath)
+			setters = append(setters, setter)
+		}
+		if len(setters) > 0 {
+			last := setters[len(setters)-1]
+			source.Source, source.Line = last.Source, last.Line
+			source.Overrides = setters[:len(setters)-1]
+		}
+		ret = append(ret, source)
+	}
+	return ret
+}
+
+// layerValueAtPath returns the value the layer sets at the path, and its path in the layer values. The globals of
+// a chart layer are copied to the subcharts, so for a path inside a subchart "global" value, the layer "global"
+// value is also used.
+func layerValueAtPath(layer valuesLayer, valuePath []string) ([]string, any, bool) {
+	layerPath, ok := trimPathPrefix(valuePath, layer.Prefix)
+	if !ok || len(layerPath) == 0 {
+		return nil, nil, false
+	}
+	for idx, item := range layerPath {
+		if idx > 0 && item != chartutil.GlobalKey {
+			continue
+		}
+		if value, ok := valueAtPath(layer.Values, layerPath[idx:]); ok {
+			return layerPath[idx:], value, true
+		}
+	}
+	return nil, nil, false
+}
+
+// valueLeaves returns the leafs of the values with their paths, sorted by key. Lists and empty maps are leafs.
+func valueLeaves(prefix []string, value any) iter.Seq2[[]string, any] {
+	return func(yield func([]string, any) bool) {
+		var walk func(prefix []string, value any) bool
+		walk = func(prefix []string, value any) bool {
+			m, ok := value.(map[string]any)
+			if !ok || len(m) == 0 {
+				if len(prefix) == 0 {
+					return true
+				}
+				return yield(prefix, value)
+			}
+			for key, item := range mapSortedByKey(m) {
+				if !walk(append(slices.Clone(prefix), key), item) {
+					return false
+				}
+			}
+			return true
+		}
+		walk(prefix, value)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli/values"
+)
+
+func TestValueSources(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	writeFile("c/Chart.yaml", "apiVersion: v2\nname: c\nversion: 1.0.0\n")
+	writeFile("c/values.yaml", "replicas: 1\nimage:\n  repository: nginx\n  tag: latest\n")
+	writeFile("c/templates/cm.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n")
+	a := writeFile("c/a.yaml", "image:\n  tag: a\n")
+	b := writeFile("c/b.yaml", "replicas: 3\n")
+
+	options := renderOptions{
+		ChartFolder: filepath.Join(dir, "c"),
+		Values: values.Options{
+			ValueFiles: []string{a, b},
+			Values:     []string{"image.pullPolicy=Always"},
+		},
+		Capabilities: chartutil.DefaultCapabilities,
+	}
+
+	type sourceResult struct {
+		Path, Value, Source string
+		Line                int
+		Overrides           []apiDataValueOverride
+	}
+	valueSourceResults := func(sources []apiDataValueSource) []sourceResult {
+		var ret []sourceResult
+		for _, source := range sources {
+			if len(source.Overrides) == 0 {
+				source.Overrides = nil
+			}
+			ret = append(ret, sourceResult{
+				Path:      source.Path,
+				Value:     source.Value,
+				Source:    source.Source,
+				Line:      source.Line,
+				Overrides: source.Overrides,
+			})
+		}
+		return ret
+	}
+
+	data, err := renderChart(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sourceResult{
+		{Path: "$.image.pullPolicy", Value: `"Always"`, Source: "--set image.pullPolicy=Always"},
+		{Path: "$.image.repository", Value: `"nginx"`, Source: "c/values.yaml", Line: 3},
+		{
+			Path: "$.image.tag", Value: `"a"`, Source: "a.yaml", Line: 2,
+			Overrides: []apiDataValueOverride{{Value: `"latest"`, Source: "c/values.yaml", Line: 4}},
+		},
+		{
+			Path: "$.replicas", Value: "3", Source: "b.yaml", Line: 1,
+			Overrides: []apiDataValueOverride{{Value: "1", Source: "c/values.yaml", Line: 1}},
+		},
+	}
+	if got := valueSourceResults(data.ValueSources); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// the values edited in the browser replace the value files and flags.
+	data, err = renderChartWithValues(options, map[string]any{"replicas": 5},
+		[]valuesLayer{{Source: editedValuesSource, Content: []byte("replicas: 5\n"), Values: map[string]any{"replicas": 5}}},
+		options.ReleaseOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []sourceResult{
+		{Path: "$.image.repository", Value: `"nginx"`, Source: "c/values.yaml", Line: 3},
+		{Path: "$.image.tag", Value: `"latest"`, Source: "c/values.yaml", Line: 4},
+		{
+			Path: "$.replicas", Value: "5", Source: editedValuesSource, Line: 1,
+			Overrides: []apiDataValueOverride{{Value: "1", Source: "c/values.yaml", Line: 1}},
+		},
+	}
+	if got := valueSourceResults(data.ValueSources); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
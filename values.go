@@ -0,0 +1,330 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/strvals"
+	"sigs.k8s.io/yaml"
+)
+
+// valuesOptionsFromFlags reads the value file and "--set" flags, with names starting with the prefix.
+func valuesOptionsFromFlags(command *cli.Command, prefix string) values.Options {
+	valueFilesFlag := "values"
+	if prefix != "" {
+		valueFilesFlag = prefix + "values"
+	}
+	return values.Options{
+		ValueFiles:    splitFlagValues(command.StringSlice(valueFilesFlag)),
+		Values:        command.StringSlice(prefix + "set"),
+		StringValues:  command.StringSlice(prefix + "set-string"),
+		FileValues:    command.StringSlice(prefix + "set-file"),
+		JSONValues:    command.StringSlice(prefix + "set-json"),
+		LiteralValues: command.StringSlice(prefix + "set-literal"),
+	}
+}
+
+// isEmptyValuesOptions returns whether no value file or "--set" flag was set.
+func isEmptyValuesOptions(options values.Options) bool {
+	return len(options.ValueFiles) == 0 && len(options.Values) == 0 && len(options.StringValues) == 0 &&
+		len(options.FileValues) == 0 && len(options.JSONValues) == 0 && len(options.LiteralValues) == 0
+}
+
+// loadValues reads and merges the value files, and then applies the "--set" family of flags in the same order as
+// Helm does: --set-json, --set, --set-string, --set-file and --set-literal. It also returns what each of them set, as
+// values layers in merge order.
+func loadValues(options renderOptions) (map[string]any, []valuesLayer, error) {
+	values := map[string]any{}
+	var layers []valuesLayer
+	valueFileNames := displayValueFiles(options)
+	for idx, valueFile := range options.Values.ValueFiles {
+		currentMap := map[string]interface{}{}
+
+		bytes, err := os.ReadFile(valueFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := yaml.Unmarshal(bytes, &currentMap); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %w", valueFile, err)
+		}
+		// the merged maps are changed by the following flags, so the layer keeps a copy.
+		layers = append(layers, valuesLayer{
+			Source:  valueFileNames[idx],
+			Content: bytes,
+			Values:  copyValues(currentMap).(map[string]any),
+		})
+		// Merge with the previous map
+		values = mergeMaps(values, currentMap)
+	}
+
+	for _, flag := range setValuesFlags(options.Values) {
+		for _, value := range flag.values {
+			// the flag is applied to the merged values like Helm does, as list indexes change the existing lists,
+			// and parsed alone for the layer.
+			if err := flag.parse(value, values); err != nil {
+				return nil, nil, fmt.Errorf("failed parsing %s data: %w", flag.name, err)
+			}
+			layer := valuesLayer{
+				Source: fmt.Sprintf("%s %s", flag.name, value),
+				Values: map[string]any{},
+			}
+			if err := flag.parse(value, layer.Values); err != nil {
+				return nil, nil, fmt.Errorf("failed parsing %s data: %w", flag.name, err)
+			}
+			layers = append(layers, layer)
+		}
+	}
+
+	return values, layers, nil
+}
+
+// setValuesFlag is one of the "--set" family of flags, with the function that parses one of its values into a map.
+type setValuesFlag struct {
+	name   string
+	values []string
+	parse  func(value string, dest map[string]any) error
+}
+
+// setValuesFlags returns the "--set" family of flags, in the order Helm applies them.
+func setValuesFlags(options values.Options) []setValuesFlag {
+	return []setValuesFlag{
+		{"--set-json", options.JSONValues, strvals.ParseJSON},
+		{"--set", options.Values, strvals.ParseInto},
+		{"--set-string", options.StringValues, strvals.ParseIntoString},
+		{"--set-file", options.FileValues, func(value string, dest map[string]any) error {
+			return strvals.ParseIntoFile(value, dest, func(rs []rune) (interface{}, error) {
+				bytes, err := os.ReadFile(string(rs))
+				if err != nil {
+					return nil, err
+				}
+				return string(bytes), nil
+			})
+		}},
+		{"--set-literal", options.LiteralValues, strvals.ParseLiteralInto},
+	}
+}
+
+// mergeMaps merges b into a, with the values of b taking precedence, like Helm does for the value files.
+func mergeMaps(a, b map[string]any) map[string]any {
+	out := make(map[string]any, len(a))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		if v, ok := v.(map[string]any); ok {
+			if bv, ok := out[k]; ok {
+				if bv, ok := bv.(map[string]any); ok {
+					out[k] = mergeMaps(bv, v)
+					continue
+				}
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// copyValues returns a deep copy of the maps and lists of the values.
+func copyValues(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		ret := make(map[string]any, len(v))
+		for key, item := range v {
+			ret[key] = copyValues(item)
+		}
+		return ret
+	case []any:
+		ret := make([]any, len(v))
+		for idx, item := range v {
+			ret[idx] = copyValues(item)
+		}
+		return ret
+	default:
+		return value
+	}
+}
+
+// valuesLayer is one of the sources of values. Prefix is the path where the values are merged, for subchart
+// default values. Content is the YAML source, if available, used to find the line where a value is set.
+type valuesLayer struct {
+	Source  string
+	Prefix  []string
+	Content []byte
+	Values  map[string]any
+}
+
+// chartValuesLayers returns the default values of the subcharts (deepest first) and of the chart as values layers
+// in the order they are merged, with the values imported from subcharts before the chart defaults. They are merged
+// before the layers of loadValues.
+func chartValuesLayers(cht *chart.Chart) []valuesLayer {
+	var layers []valuesLayer
+	for c := range chartAndDependencies(cht) {
+		// ProcessDependencies replaces the values of the charts with dependencies with the coalesced values, so the
+		// values file is used.
+		layer := valuesLayer{
+			Source: path.Join(c.ChartFullPath(), chartutil.ValuesfileName),
+			Values: map[string]any{},
+		}
+		for p := c; p.Parent() != nil; p = p.Parent() {
+			layer.Prefix = append([]string{p.Name()}, layer.Prefix...)
+		}
+		for _, f := range c.Raw {
+			if f.Name == chartutil.ValuesfileName {
+				layer.Content = f.Data
+				_ = yaml.Unmarshal(f.Data, &layer.Values)
+			}
+		}
+		layers = append(layers, importValuesLayers(c, layer.Prefix)...)
+		layers = append(layers, layer)
+	}
+	slices.SortStableFunc(layers, func(a, b valuesLayer) int {
+		return cmp.Compare(len(b.Prefix), len(a.Prefix))
+	})
+	return layers
+}
+
+// importValuesLayers returns the values imported from the default values of the subcharts with "import-values".
+// It must be called after ProcessDependencies, which sets them in the "child" and "parent" form.
+func importValuesLayers(c *chart.Chart, prefix []string) []valuesLayer {
+	var ret []valuesLayer
+	for _, dep := range c.Metadata.Dependencies {
+		for _, importValue := range dep.ImportValues {
+			iv, ok := importValue.(map[string]string)
+			if !ok {
+				continue
+			}
+			idx := slices.IndexFunc(c.Dependencies(), func(subchart *chart.Chart) bool {
+				return subchart.Name() == dep.Name
+			})
+			if idx < 0 {
+				continue
+			}
+			subchartValues := map[string]any{}
+			for _, f := range c.Dependencies()[idx].Raw {
+				if f.Name == chartutil.ValuesfileName {
+					_ = yaml.Unmarshal(f.Data, &subchartValues)
+				}
+			}
+			value, ok := valueAtPath(subchartValues, splitValuePath(iv["child"]))
+			if !ok {
+				continue
+			}
+			layer := valuesLayer{
+				Source: fmt.Sprintf("import-values %s.%s -> %s", dep.Name, iv["child"], iv["parent"]),
+				Prefix: prefix,
+				Values: map[string]any{},
+			}
+			parentPath := splitValuePath(iv["parent"])
+			if len(parentPath) == 0 {
+				valueMap, ok := value.(map[string]any)
+				if !ok {
+					continue
+				}
+				layer.Values = valueMap
+			} else {
+				current := layer.Values
+				for _, item := range parentPath[:len(parentPath)-1] {
+					next := map[string]any{}
+					current[item] = next
+					current = next
+				}
+				current[parentPath[len(parentPath)-1]] = value
+			}
+			ret = append(ret, layer)
+		}
+	}
+	return ret
+}
+
+// splitValuePath splits a dotted values path, where "." is the root.
+func splitValuePath(valuePath string) []string {
+	var ret []string
+	for _, item := range strings.Split(valuePath, ".") {
+		if item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+// locateValue returns the last layer that sets the value at the path, and the line where it is set if the layer
+// has YAML content. If no layer sets the full path, the closest parent path that is set is used.
+func locateValue(layers []valuesLayer, valuePath []string) (string, int, bool) {
+	for current := valuePath; len(current) > 0; current = current[:len(current)-1] {
+		for _, layer := range slices.Backward(layers) {
+			layerPath, ok := trimPathPrefix(current, layer.Prefix)
+			if !ok || len(layerPath) == 0 || !hasValuePath(layer.Values, layerPath) {
+				continue
+			}
+			line, _ := yamlPathLine(layer.Content, layerPath)
+			return layer.Source, line, true
+		}
+	}
+	return "", 0, false
+}
+
+// hasValuePath returns whether the values contain the path. Numeric path items are used as list indexes.
+func hasValuePath(values map[string]any, valuePath []string) bool {
+	_, ok := valueAtPath(values, valuePath)
+	return ok
+}
+
+// valueAtPath returns the value at the path. Numeric path items are used as list indexes.
+func valueAtPath(values map[string]any, valuePath []string) (any, bool) {
+	var current any = values
+	for _, item := range valuePath {
+		switch v := current.(type) {
+		case map[string]any:
+			next, ok := v[item]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []any:
+			idx, err := strconv.Atoi(item)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+			current = v[idx]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func trimPathPrefix(valuePath []string, prefix []string) ([]string, bool) {
+	if len(valuePath) < len(prefix) || !slices.Equal(valuePath[:len(prefix)], prefix) {
+		return nil, false
+	}
+	return valuePath[len(prefix):], true
+}
+
+func displayValueFiles(options renderOptions) []string {
+	var ret []string
+	for _, valueFile := range options.Values.ValueFiles {
+		ret = append(ret, ensureRelativePath(strings.TrimPrefix(valueFile, options.ChartFolder)))
+	}
+	return ret
+}
+
+// displaySetValues returns the "--set" family of flags, in the order they are applied.
+func displaySetValues(options renderOptions) []string {
+	var ret []string
+	for _, flag := range setValuesFlags(options.Values) {
+		for _, value := range flag.values {
+			ret = append(ret, fmt.Sprintf("%s %s", flag.name, value))
+		}
+	}
+	return ret
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+func TestLoadValuesPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	a := writeFile("a.yaml", "replicas: 2\nimage:\n  repository: nginx\n  tag: a\n")
+	b := writeFile("b.yaml", "replicas: 3\nimage:\n  tag: b\n")
+	content := writeFile("content.txt", "from file")
+
+	tests := []struct {
+		name    string
+		options values.Options
+		want    map[string]any
+	}{
+		{
+			name:    "last value file wins",
+			options: values.Options{ValueFiles: []string{a, b}},
+			want: map[string]any{
+				"replicas": float64(3),
+				"image":    map[string]any{"repository": "nginx", "tag": "b"},
+			},
+		},
+		{
+			name: "set-json before set",
+			options: values.Options{
+				ValueFiles: []string{a},
+				JSONValues: []string{`replicas=4`},
+				Values:     []string{"replicas=5"},
+			},
+			want: map[string]any{
+				"replicas": int64(5),
+				"image":    map[string]any{"repository": "nginx", "tag": "a"},
+			},
+		},
+		{
+			name: "set-string after set",
+			options: values.Options{
+				Values:       []string{"replicas=5"},
+				StringValues: []string{"replicas=6"},
+			},
+			want: map[string]any{"replicas": "6"},
+		},
+		{
+			name: "set-file after set-string",
+			options: values.Options{
+				StringValues: []string{"data=string"},
+				FileValues:   []string{"data=" + content},
+			},
+			want: map[string]any{"data": "from file"},
+		},
+		{
+			name: "set-literal last",
+			options: values.Options{
+				ValueFiles:    []string{a, b},
+				JSONValues:    []string{`image={"tag":"json"}`},
+				Values:        []string{"image.tag=set"},
+				StringValues:  []string{"image.tag=string"},
+				FileValues:    []string{"image.tag=" + content},
+				LiteralValues: []string{"image.tag=a,b"},
+			},
+			want: map[string]any{
+				"replicas": float64(3),
+				"image":    map[string]any{"tag": "a,b"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, _, err := loadValues(renderOptions{Values: test.options})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+
+			helmValues, err := test.options.MergeValues(getter.Providers{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, helmValues) {
+				t.Errorf("got %#v, Helm merges %#v", got, helmValues)
+			}
+		})
+	}
+}
+
+func TestLoadValuesLayers(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	a := writeFile("a.yaml", "image:\n  repository: nginx\n  tag: a\n")
+	content := writeFile("content.txt", "from file")
+
+	options := renderOptions{
+		ChartFolder: dir,
+		Values: values.Options{
+			ValueFiles:    []string{a},
+			Values:        []string{"image.pullPolicy=Always"},
+			FileValues:    []string{"data=" + content},
+			LiteralValues: []string{"image.tag=b"},
+		},
+	}
+	_, layers, err := loadValues(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type layerResult struct {
+		Source  string
+		Content string
+		Values  map[string]any
+	}
+	var got []layerResult
+	for _, layer := range layers {
+		got = append(got, layerResult{Source: layer.Source, Content: string(layer.Content), Values: layer.Values})
+	}
+	want := []layerResult{
+		{
+			Source:  "a.yaml",
+			Content: "image:\n  repository: nginx\n  tag: a\n",
+			// not changed by the following flags.
+			Values: map[string]any{"image": map[string]any{"repository": "nginx", "tag": "a"}},
+		},
+		{
+			Source: "--set image.pullPolicy=Always",
+			Values: map[string]any{"image": map[string]any{"pullPolicy": "Always"}},
+		},
+		{
+			Source: "--set-file data=" + content,
+			Values: map[string]any{"data": "from file"},
+		},
+		{
+			Source: "--set-literal image.tag=b",
+			Values: map[string]any{"image": map[string]any{"tag": "b"}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestLoadValuesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		options values.Options
+	}{
+		{name: "set", options: values.Options{Values: []string{"a[=1"}}},
+		{name: "set-json", options: values.Options{JSONValues: []string{"a={"}}},
+		{name: "set-file", options: values.Options{FileValues: []string{"a=" + filepath.Join(t.TempDir(), "missing")}}},
+		{name: "value file", options: values.Options{ValueFiles: []string{filepath.Join(t.TempDir(), "missing.yaml")}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := loadValues(renderOptions{Values: test.options}); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+const watchDebounce = 300 * time.Millisecond
+
+// watchChart watches the chart folder, the value files and the local "file://" subcharts, calling onChange after
+// changes stop happening for a short period.
+func watchChart(ctx context.Context, options renderOptions, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	valueFiles := map[string]bool{}
+	watchValueFiles := options.Values.ValueFiles
+	if options.CompareValues != nil {
+		watchValueFiles = append(slices.Clone(watchValueFiles), options.CompareValues.ValueFiles...)
+	}
+	for _, valueFile := range watchValueFiles {
+		absValueFile, err := filepath.Abs(valueFile)
+		if err != nil {
+			_ = watcher.Close()
+			return err
+		}
+		valueFiles[absValueFile] = true
+		// editors usually replace the file instead of writing to it, so watch the parent folder.
+		if err := watcher.Add(filepath.Dir(absValueFile)); err != nil {
+			_ = watcher.Close()
+			return err
+		}
+	}
+
+	// the chart folders are only searched again when a "Chart.yaml" changes.
+	chartFolders := absWatchChartFolders(options.ChartFolder)
+	for _, folder := range chartFolders {
+		if err := addWatchTree(watcher, folder); err != nil {
+			_ = watcher.Close()
+			return err
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Chmod) {
+					continue
+				}
+				if event.Has(fsnotify.Create) {
+					if st, err := os.Stat(event.Name); err == nil && st.IsDir() {
+						_ = addWatchTree(watcher, event.Name)
+					}
+				}
+				if _, isValueFile := valueFiles[event.Name]; !isValueFile && !isWatchedChartPath(chartFolders, event.Name) {
+					continue
+				}
+				if filepath.Base(event.Name) == chartutil.ChartfileName {
+					chartFolders = absWatchChartFolders(options.ChartFolder)
+					for _, folder := range chartFolders {
+						_ = addWatchTree(watcher, folder)
+					}
+				}
+				debounce = time.After(watchDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.WarnContext(ctx, "error watching files", "error", err)
+			case <-debounce:
+				debounce = nil
+				onChange()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// watchChartFolders returns the chart folder and the folders of all local "file://" subcharts, recursively.
+func watchChartFolders(chartFolder string) []string {
+	folders := []string{chartFolder}
+	metadata, err := chartutil.LoadChartfile(filepath.Join(chartFolder, chartutil.ChartfileName))
+	if err != nil {
+		return folders
+	}
+	for depFolder := range localDependencyFolders(chartFolder, metadata) {
+		folders = append(folders, watchChartFolders(depFolder)...)
+	}
+	return folders
+}
+
+// absWatchChartFolders returns the absolute paths of watchChartFolders.
+func absWatchChartFolders(chartFolder string) []string {
+	var ret []string
+	for _, folder := range watchChartFolders(chartFolder) {
+		if absFolder, err := filepath.Abs(folder); err == nil {
+			ret = append(ret, absFolder)
+		}
+	}
+	return ret
+}
+
+// isWatchedChartPath returns whether the file is inside one of the absolute chart folders. Value files are handled
+// separately, as only their parent folder is watched.
+func isWatchedChartPath(chartFolders []string, name string) bool {
+	for _, folder := range chartFolders {
+		if rel, err := filepath.Rel(folder, name); err == nil && filepath.IsLocal(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// addWatchTree adds the folder and all of its subfolders to the watcher.
+func addWatchTree(watcher *fsnotify.Watcher, folder string) error {
+	absFolder, err := filepath.Abs(folder)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(absFolder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
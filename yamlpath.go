@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strconv"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+)
+
+// yamlPathLine returns the line where the value at the path is set in the YAML document. If the full path is not
+// present, it returns false.
+func yamlPathLine(content []byte, path []string) (int, bool) {
+	return yamlNodePathLine(parseYAMLDocument(content), path)
+}
+
+// parseYAMLDocument returns the root node of the YAML document, or nil if it can't be parsed.
+func parseYAMLDocument(content []byte) *yamlv3.Node {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(content, &doc); err != nil {
+		return nil
+	}
+	if doc.Kind != yamlv3.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// yamlNodePathLine is like yamlPathLine, for an already parsed document.
+func yamlNodePathLine(node *yamlv3.Node, path []string) (int, bool) {
+	if node == nil {
+		return 0, false
+	}
+	line := node.Line
+	for _, item := range path {
+		node = resolveYAMLAlias(node)
+		switch node.Kind {
+		case yamlv3.MappingNode:
+			var found *yamlv3.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == item {
+					line = node.Content[i].Line
+					found = node.Content[i+1]
+				}
+			}
+			if found == nil {
+				return 0, false
+			}
+			node = found
+		case yamlv3.SequenceNode:
+			idx, err := strconv.Atoi(item)
+			if err != nil || idx < 0 || idx >= len(node.Content) {
+				return 0, false
+			}
+			node = node.Content[idx]
+			line = node.Line
+		default:
+			return 0, false
+		}
+	}
+	return line, true
+}
+
+func resolveYAMLAlias(node *yamlv3.Node) *yamlv3.Node {
+	for node.Kind == yamlv3.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}